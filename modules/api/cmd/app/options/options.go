@@ -24,13 +24,21 @@ import (
 	"strings"
 
 	"github.com/kubesphere-extensions/gateway-api/pkg/apiserver"
+	"github.com/kubesphere-extensions/gateway-api/pkg/apiserver/authentication"
+	"github.com/kubesphere-extensions/gateway-api/pkg/apiserver/impersonation"
 	apiserverconfig "github.com/kubesphere-extensions/gateway-api/pkg/config"
 	"github.com/kubesphere-extensions/gateway-api/pkg/scheme"
 
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
 	cliflag "k8s.io/component-base/cli/flag"
 	"k8s.io/klog/v2"
 	ctrl "sigs.k8s.io/controller-runtime"
-	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	metricsserver "sigs.k8s.io/controller-runtime/pkg/metrics/server"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
 
 	genericoptions "github.com/kubesphere-extensions/gateway-api/pkg/server/options"
 )
@@ -38,6 +46,7 @@ import (
 type ServerRunOptions struct {
 	ConfigFile              string
 	GenericServerRunOptions *genericoptions.ServerRunOptions
+	ManagerOptions          *genericoptions.ManagerOptions
 
 	*apiserverconfig.Config
 
@@ -48,6 +57,7 @@ type ServerRunOptions struct {
 func NewServerRunOptions() *ServerRunOptions {
 	s := &ServerRunOptions{
 		GenericServerRunOptions: genericoptions.NewServerRunOptions(),
+		ManagerOptions:          genericoptions.NewManagerOptions(),
 	}
 
 	return s
@@ -59,6 +69,9 @@ func (s *ServerRunOptions) Flags() (fss cliflag.NamedFlagSets) {
 		"ks-apiserver will listen on a random port on 127.0.0.1, then you can use the gops tool to list and diagnose the ks-apiserver currently running.")
 	s.GenericServerRunOptions.AddFlags(fs, s.GenericServerRunOptions)
 
+	fs = fss.FlagSet("manager")
+	s.ManagerOptions.AddFlags(fs, s.ManagerOptions)
+
 	fs = fss.FlagSet("klog")
 	local := flag.NewFlagSet("klog", flag.ExitOnError)
 	klog.InitFlags(local)
@@ -90,12 +103,69 @@ func (s *ServerRunOptions) NewAPIServer() (*apiserver.APIServer, error) {
 		server.Addr = fmt.Sprintf(":%d", s.GenericServerRunOptions.SecurePort)
 	}
 
+	if caFile := s.GenericServerRunOptions.RequestHeaderClientCAFile; caFile != "" {
+		if server.TLSConfig == nil {
+			return nil, fmt.Errorf("requestheader-client-ca-file requires secure serving")
+		}
+		requestHeader, err := authentication.NewRequestHeader(caFile, s.GenericServerRunOptions.RequestHeaderAllowedNames)
+		if err != nil {
+			return nil, err
+		}
+		// the client certificates are verified by the authentication against
+		// the requestheader client CA, the clients without them use tokens
+		server.TLSConfig.ClientAuth = tls.RequestClientCert
+		apiServer.RequestHeader = requestHeader
+	}
+
 	apiServer.Server = server
 
-	var err error
-	apiServer.RuntimeClient, err = ctrlclient.New(ctrl.GetConfigOrDie(), ctrlclient.Options{Scheme: scheme.Scheme})
+	if errs := s.ManagerOptions.Validate(); len(errs) != 0 {
+		return nil, utilerrors.NewAggregate(errs)
+	}
+
+	mgrOptions := manager.Options{
+		Scheme: scheme.Scheme,
+		Metrics: metricsserver.Options{
+			BindAddress: s.ManagerOptions.MetricsBindAddress,
+		},
+		// health checks are served by the APIServer itself
+		HealthProbeBindAddress:  "0",
+		LeaderElection:          s.ManagerOptions.LeaderElect,
+		LeaderElectionID:        "gateway-apiserver-leader-election",
+		LeaderElectionNamespace: s.ManagerOptions.LeaderElectionNamespace,
+		Client: client.Options{
+			Cache: &client.CacheOptions{
+				// Secrets, ConfigMaps and the data plane workloads are read on
+				// demand instead of caching all of them in the cluster
+				DisableFor: []client.Object{&corev1.Secret{}, &corev1.ConfigMap{}, &corev1.Service{}, &appsv1.Deployment{}},
+			},
+		},
+	}
+	if s.ManagerOptions.WebhookPort != 0 {
+		mgrOptions.WebhookServer = webhook.NewServer(webhook.Options{
+			Port:    s.ManagerOptions.WebhookPort,
+			CertDir: s.ManagerOptions.WebhookCertDir,
+		})
+	}
+
+	clientMode := impersonation.Mode(s.GenericServerRunOptions.ClientMode)
+	if clientMode != impersonation.ModeServiceAccount && !s.GenericServerRunOptions.EnableAuthorization {
+		return nil, fmt.Errorf("client mode %s requires enable-authorization", clientMode)
+	}
+
+	config := ctrl.GetConfigOrDie()
+	mgr, err := manager.New(config, mgrOptions)
+	if err != nil {
+		klog.Fatalf("unable to create controller runtime manager: %v", err)
+	}
+	apiServer.Manager = mgr
+	apiServer.EnableWebhook = s.ManagerOptions.WebhookPort != 0
+	// read from the informer cache of manager, which is shared by all requests
+	apiServer.RuntimeClient = mgr.GetClient()
+	apiServer.EnableAuthorization = s.GenericServerRunOptions.EnableAuthorization
+	apiServer.RequestClient, err = impersonation.NewClient(config, apiServer.RuntimeClient, clientMode)
 	if err != nil {
-		klog.Fatalf("unable to create controller runtime client: %v", err)
+		return nil, err
 	}
 
 	return apiServer, nil
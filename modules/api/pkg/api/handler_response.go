@@ -1,12 +1,16 @@
 package api
 
 import (
-	"github.com/gin-gonic/gin"
+	goerrors "errors"
 	"net/http"
 	"runtime"
 	"strings"
 
+	"github.com/gin-gonic/gin"
 	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/validation/field"
 	"k8s.io/klog/v2"
 )
 
@@ -42,6 +46,10 @@ func HandleConflict(c *gin.Context, err error) {
 	handle(http.StatusConflict, c, err)
 }
 
+func HandleUnsupportedMediaType(c *gin.Context, err error) {
+	handle(http.StatusUnsupportedMediaType, c, err)
+}
+
 func HandleError(c *gin.Context, err error) {
 	var statusCode int
 	switch t := err.(type) {
@@ -56,5 +64,97 @@ func HandleError(c *gin.Context, err error) {
 func handle(statusCode int, c *gin.Context, err error) {
 	_, fn, line, _ := runtime.Caller(2)
 	klog.Errorf("%s:%d %v", fn, line, err)
-	c.JSON(statusCode, err)
+	status := newStatus(statusCode, err)
+	c.JSON(int(status.Code), status)
+}
+
+// newStatus converts err to a Status, the Status of APIStatus errors is kept
+// unchanged, so that its code agrees with its reason, and field errors are
+// reported as Invalid with causes.
+func newStatus(statusCode int, err error) *metav1.Status {
+	var status metav1.Status
+	var apiStatus errors.APIStatus
+	if fieldErrs := fieldErrors(err); len(fieldErrs) != 0 {
+		status = metav1.Status{
+			Status:  metav1.StatusFailure,
+			Code:    http.StatusUnprocessableEntity,
+			Reason:  metav1.StatusReasonInvalid,
+			Message: err.Error(),
+			Details: &metav1.StatusDetails{},
+		}
+		for _, fieldErr := range fieldErrs {
+			status.Details.Causes = append(status.Details.Causes, metav1.StatusCause{
+				Type:    metav1.CauseType(fieldErr.Type),
+				Message: fieldErr.ErrorBody(),
+				Field:   fieldErr.Field,
+			})
+		}
+	} else if goerrors.As(err, &apiStatus) {
+		status = apiStatus.Status()
+		if status.Code == 0 {
+			status.Code = int32(statusCode)
+		}
+	} else {
+		status = metav1.Status{
+			Status:  metav1.StatusFailure,
+			Code:    int32(statusCode),
+			Reason:  reasonForCode(statusCode),
+			Message: err.Error(),
+		}
+	}
+
+	status.TypeMeta = metav1.TypeMeta{Kind: "Status", APIVersion: "v1"}
+	status.Message = sanitizer.Replace(status.Message)
+	if status.Details != nil {
+		for i := range status.Details.Causes {
+			status.Details.Causes[i].Message = sanitizer.Replace(status.Details.Causes[i].Message)
+		}
+	}
+	return &status
+}
+
+// fieldErrors returns the field errors of err, which is either a field error
+// or an aggregate of field errors.
+func fieldErrors(err error) field.ErrorList {
+	var fieldErr *field.Error
+	if goerrors.As(err, &fieldErr) {
+		return field.ErrorList{fieldErr}
+	}
+	var aggregate utilerrors.Aggregate
+	if !goerrors.As(err, &aggregate) {
+		return nil
+	}
+	fieldErrs := field.ErrorList{}
+	for _, e := range aggregate.Errors() {
+		if !goerrors.As(e, &fieldErr) {
+			return nil
+		}
+		fieldErrs = append(fieldErrs, fieldErr)
+	}
+	return fieldErrs
+}
+
+func reasonForCode(code int) metav1.StatusReason {
+	switch code {
+	case http.StatusBadRequest:
+		return metav1.StatusReasonBadRequest
+	case http.StatusUnauthorized:
+		return metav1.StatusReasonUnauthorized
+	case http.StatusForbidden:
+		return metav1.StatusReasonForbidden
+	case http.StatusNotFound:
+		return metav1.StatusReasonNotFound
+	case http.StatusConflict:
+		return metav1.StatusReasonConflict
+	case http.StatusUnsupportedMediaType:
+		return metav1.StatusReasonUnsupportedMediaType
+	case http.StatusUnprocessableEntity:
+		return metav1.StatusReasonInvalid
+	case http.StatusTooManyRequests:
+		return metav1.StatusReasonTooManyRequests
+	case http.StatusInternalServerError:
+		return metav1.StatusReasonInternalError
+	default:
+		return metav1.StatusReasonUnknown
+	}
 }
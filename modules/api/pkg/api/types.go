@@ -0,0 +1,7 @@
+package api
+
+// ListResult is the KubeSphere style envelope of list responses
+type ListResult struct {
+	Items      interface{} `json:"items"`
+	TotalItems int         `json:"totalItems"`
+}
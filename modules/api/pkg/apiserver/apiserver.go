@@ -7,12 +7,20 @@ import (
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/kubesphere-extensions/gateway-api/pkg/apiserver/authentication"
+	"github.com/kubesphere-extensions/gateway-api/pkg/apiserver/authorization"
+	"github.com/kubesphere-extensions/gateway-api/pkg/controller"
 	"github.com/kubesphere-extensions/gateway-api/pkg/kapis/v1alpha1"
 	"k8s.io/klog/v2"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
 	rtclient "sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/healthz"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
 )
 
+// cacheSyncCheckTimeout bounds the wait of readiness check for the caches.
+const cacheSyncCheckTimeout = time.Second
+
 type APIServer struct {
 	Server *http.Server
 
@@ -21,6 +29,34 @@ type APIServer struct {
 
 	// controller-runtime client
 	RuntimeClient rtclient.Client
+
+	// controller-runtime client used by the API handlers, which may do the writes as the caller
+	RequestClient rtclient.Client
+
+	// controller-runtime manager, which runs the informer cache, webhooks and controllers
+	Manager manager.Manager
+
+	// register the admission webhooks to the webhook server of manager or not
+	EnableWebhook bool
+
+	// authenticate the caller and authorize requests or not
+	EnableAuthorization bool
+
+	// verifies the front proxies which forward the identity of caller by
+	// headers, nil if the identity headers are not accepted
+	RequestHeader *authentication.RequestHeader
+}
+
+func (s *APIServer) installControllers() error {
+	if s.EnableWebhook {
+		if err := (&controller.GatewayWebhook{}).SetupWithWebhook(s.Manager); err != nil {
+			return err
+		}
+	}
+	if err := (&controller.CanaryReconciler{}).SetupWithManager(s.Manager); err != nil {
+		return err
+	}
+	return nil
 }
 
 func (s *APIServer) installAPIs() {
@@ -28,11 +64,34 @@ func (s *APIServer) installAPIs() {
 	s.Engine.GET("/healthz", func(c *gin.Context) {
 		_ = healthz.Ping(c.Request)
 	})
+	// ready once the informer caches, which the APIs read from, are synced
 	s.Engine.GET("/readyz", func(c *gin.Context) {
+		if s.Manager != nil {
+			ctx, cancel := context.WithTimeout(c.Request.Context(), cacheSyncCheckTimeout)
+			defer cancel()
+			if !s.Manager.GetCache().WaitForCacheSync(ctx) {
+				c.String(http.StatusServiceUnavailable, "informer caches are not synced")
+				return
+			}
+		}
 		_ = healthz.Ping(c.Request)
 	})
 
-	v1alpha1.AddRouterGroup(s.Engine, s.RuntimeClient)
+	var authorizer authorization.Authorizer
+	var middlewares []gin.HandlerFunc
+	if s.EnableAuthorization {
+		authorizer = authorization.NewAuthorizer(s.RuntimeClient)
+		middlewares = append(middlewares, authentication.Authenticate(s.RuntimeClient, s.RequestHeader), authorization.Authorize(authorizer, v1alpha1.FilteredResources...))
+	}
+	requestClient := s.RequestClient
+	if requestClient == nil {
+		requestClient = s.RuntimeClient
+	}
+	var informers cache.Informers
+	if s.Manager != nil {
+		informers = s.Manager.GetCache()
+	}
+	v1alpha1.AddRouterGroup(s.Engine, requestClient, s.RuntimeClient, authorizer, informers, middlewares...)
 }
 
 func (s *APIServer) PrepareRun() error {
@@ -56,7 +115,7 @@ func (s *APIServer) PrepareRun() error {
 
 	s.Server.Handler = s.Engine
 
-	return nil
+	return s.installControllers()
 }
 
 func (s *APIServer) Run(ctx context.Context) error {
@@ -65,11 +124,32 @@ func (s *APIServer) Run(ctx context.Context) error {
 		_ = s.Server.Shutdown(ctx)
 	}()
 
+	mgrErr := make(chan error, 1)
+	go func() {
+		klog.Info("Start controller-runtime manager")
+		err := s.Manager.Start(ctx)
+		mgrErr <- err
+		if err != nil {
+			_ = s.Server.Shutdown(context.Background())
+		}
+	}()
+
 	s.Server.Handler = s.Engine
 
 	klog.Infof("Start listening on %s", s.Server.Addr)
-	if err := s.Server.ListenAndServe(); err != nil {
-		return err
+	var err error
+	if s.Server.TLSConfig != nil {
+		// the certificate is loaded into TLSConfig
+		err = s.Server.ListenAndServeTLS("", "")
+	} else {
+		err = s.Server.ListenAndServe()
 	}
-	return nil
+	select {
+	case mErr := <-mgrErr:
+		if mErr != nil {
+			return mErr
+		}
+	default:
+	}
+	return err
 }
@@ -0,0 +1,141 @@
+package authentication
+
+import (
+	"context"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kubesphere-extensions/gateway-api/pkg/api"
+	"github.com/kubesphere-extensions/gateway-api/pkg/apiserver/request"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	rtclient "sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// HeaderRemoteUser, HeaderRemoteGroup and HeaderRemoteExtraPrefix carry the
+	// identity of caller forwarded by the KubeSphere apiserver.
+	HeaderRemoteUser        = "X-Remote-User"
+	HeaderRemoteGroup       = "X-Remote-Group"
+	HeaderRemoteExtraPrefix = "X-Remote-Extra-"
+
+	headerAuthorization = "Authorization"
+	bearerPrefix        = "Bearer "
+)
+
+// RequestHeader verifies the front proxy which forwards the identity of caller
+// by the X-Remote-* headers, the headers of other clients are ignored.
+type RequestHeader struct {
+	// clientCA verifies the client certificates of front proxies
+	clientCA *x509.CertPool
+	// allowedNames are the common names of the client certificates of front
+	// proxies, any name verified by clientCA is allowed if empty
+	allowedNames []string
+}
+
+// NewRequestHeader returns the verifier of front proxies whose client
+// certificates are signed by the CA of caFile.
+func NewRequestHeader(caFile string, allowedNames []string) (*RequestHeader, error) {
+	data, err := os.ReadFile(caFile)
+	if err != nil {
+		return nil, err
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(data) {
+		return nil, fmt.Errorf("no certificate found in requestheader client CA file %s", caFile)
+	}
+	return &RequestHeader{clientCA: pool, allowedNames: allowedNames}, nil
+}
+
+// verify reports whether the request comes from a front proxy by mTLS.
+func (r *RequestHeader) verify(req *http.Request) bool {
+	if r == nil || req.TLS == nil || len(req.TLS.PeerCertificates) == 0 {
+		return false
+	}
+	intermediates := x509.NewCertPool()
+	for _, certificate := range req.TLS.PeerCertificates[1:] {
+		intermediates.AddCert(certificate)
+	}
+	leaf := req.TLS.PeerCertificates[0]
+	_, err := leaf.Verify(x509.VerifyOptions{
+		Roots:         r.clientCA,
+		Intermediates: intermediates,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	})
+	if err != nil {
+		return false
+	}
+	return len(r.allowedNames) == 0 || slices.Contains(r.allowedNames, leaf.Subject.CommonName)
+}
+
+// Authenticate returns a middleware which resolves the caller of request from
+// the identity headers forwarded by a front proxy verified by requestHeader,
+// or from the bearer token by a TokenReview, and carries the user in the
+// context of request. The identity headers are ignored if requestHeader is nil.
+func Authenticate(client rtclient.Client, requestHeader *RequestHeader) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user, token, err := authenticate(c.Request.Context(), client, requestHeader, c.Request)
+		if err != nil {
+			api.HandleUnauthorized(c, err)
+			c.Abort()
+			return
+		}
+		ctx := request.WithUser(c.Request.Context(), user)
+		if token != "" {
+			ctx = request.WithToken(ctx, token)
+		}
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+	}
+}
+
+// authenticate returns the caller of request, and the bearer token if the
+// caller is authenticated by it.
+func authenticate(ctx context.Context, client rtclient.Client, requestHeader *RequestHeader, req *http.Request) (*authenticationv1.UserInfo, string, error) {
+	if name := req.Header.Get(HeaderRemoteUser); name != "" && requestHeader.verify(req) {
+		user := &authenticationv1.UserInfo{
+			Username: name,
+			Groups:   req.Header.Values(HeaderRemoteGroup),
+		}
+		for header, values := range req.Header {
+			if !strings.HasPrefix(header, HeaderRemoteExtraPrefix) {
+				continue
+			}
+			if user.Extra == nil {
+				user.Extra = map[string]authenticationv1.ExtraValue{}
+			}
+			extraKey := strings.ToLower(strings.TrimPrefix(header, HeaderRemoteExtraPrefix))
+			user.Extra[extraKey] = values
+		}
+		return user, "", nil
+	}
+
+	token := BearerToken(req)
+	if token == "" {
+		return nil, "", fmt.Errorf("the caller of request is unknown")
+	}
+	review := &authenticationv1.TokenReview{
+		Spec: authenticationv1.TokenReviewSpec{Token: token},
+	}
+	err := client.Create(ctx, review)
+	if err != nil {
+		return nil, "", err
+	}
+	if !review.Status.Authenticated {
+		return nil, "", fmt.Errorf("invalid bearer token: %s", review.Status.Error)
+	}
+	return &review.Status.User, token, nil
+}
+
+// BearerToken returns the bearer token of request, or empty if there is none.
+func BearerToken(req *http.Request) string {
+	auth := strings.TrimSpace(req.Header.Get(headerAuthorization))
+	if !strings.HasPrefix(auth, bearerPrefix) {
+		return ""
+	}
+	return strings.TrimSpace(strings.TrimPrefix(auth, bearerPrefix))
+}
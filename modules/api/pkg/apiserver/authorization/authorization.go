@@ -0,0 +1,111 @@
+package authorization
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kubesphere-extensions/gateway-api/pkg/api"
+	"github.com/kubesphere-extensions/gateway-api/pkg/apiserver/request"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	rtclient "sigs.k8s.io/controller-runtime/pkg/client"
+	apisv1 "sigs.k8s.io/gateway-api/apis/v1"
+)
+
+type Authorizer interface {
+	// Authorize reports whether the user may do what the attributes describe,
+	// and the reason of decision.
+	Authorize(ctx context.Context, user *authenticationv1.UserInfo, attrs *request.Attributes) (bool, string, error)
+}
+
+type subjectAccessReviewAuthorizer struct {
+	client rtclient.Client
+}
+
+// NewAuthorizer returns an Authorizer which asks the Kubernetes apiserver by
+// SubjectAccessReviews. The namespace scoped requests are reviewed in their
+// namespaces, and the others in cluster scope, since workspaces are unknown to
+// Kubernetes RBAC, the gateways of a workspace are selected by their scope
+// labels after the request is authorized.
+func NewAuthorizer(client rtclient.Client) Authorizer {
+	return &subjectAccessReviewAuthorizer{client: client}
+}
+
+func (a *subjectAccessReviewAuthorizer) Authorize(ctx context.Context, user *authenticationv1.UserInfo, attrs *request.Attributes) (bool, string, error) {
+	review := &authorizationv1.SubjectAccessReview{
+		Spec: authorizationv1.SubjectAccessReviewSpec{
+			User:   user.Username,
+			Groups: user.Groups,
+			UID:    user.UID,
+		},
+	}
+	if len(user.Extra) != 0 {
+		review.Spec.Extra = make(map[string]authorizationv1.ExtraValue, len(user.Extra))
+		for k, v := range user.Extra {
+			review.Spec.Extra[k] = authorizationv1.ExtraValue(v)
+		}
+	}
+
+	group := attrs.Group
+	if group == "" {
+		group = apisv1.GroupName
+	}
+	review.Spec.ResourceAttributes = &authorizationv1.ResourceAttributes{
+		Namespace:   attrs.Namespace,
+		Verb:        attrs.Verb,
+		Group:       group,
+		Resource:    attrs.Resource,
+		Subresource: attrs.Subresource,
+		Name:        attrs.Name,
+	}
+
+	err := a.client.Create(ctx, review)
+	if err != nil {
+		return false, "", err
+	}
+	return review.Status.Allowed && !review.Status.Denied, review.Status.Reason, nil
+}
+
+// Authorize returns a middleware which rejects the requests that the caller
+// is not allowed to do. The cluster scoped lists of filtered resources are
+// let through, their handlers only return the items which the caller may see.
+func Authorize(authorizer Authorizer, filtered ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user, ok := request.UserFrom(c.Request.Context())
+		if !ok {
+			api.HandleUnauthorized(c, fmt.Errorf("the caller of request is unknown"))
+			c.Abort()
+			return
+		}
+
+		attrs := request.NewAttributes(c)
+		allowed, reason, err := authorizer.Authorize(c.Request.Context(), user, attrs)
+		if err != nil {
+			api.HandleInternalError(c, err)
+			c.Abort()
+			return
+		}
+		if allowed {
+			c.Next()
+			return
+		}
+		if attrs.ClusterScoped() && (attrs.Verb == request.VerbList || attrs.Verb == request.VerbWatch) {
+			for _, resource := range filtered {
+				if resource == attrs.Resource && attrs.Subresource == "" {
+					c.Next()
+					return
+				}
+			}
+		}
+
+		if reason == "" {
+			reason = fmt.Sprintf("user %q cannot %s %s", user.Username, attrs.Verb, attrs.Resource)
+		}
+		resource := schema.GroupResource{Group: apisv1.GroupName, Resource: attrs.Resource}
+		api.HandleForbidden(c, errors.NewForbidden(resource, attrs.Name, fmt.Errorf("%s", reason)))
+		c.Abort()
+	}
+}
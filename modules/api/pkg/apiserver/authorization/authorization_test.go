@@ -0,0 +1,128 @@
+package authorization
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kubesphere-extensions/gateway-api/pkg/apiserver/request"
+	"github.com/kubesphere-extensions/gateway-api/pkg/scheme"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	rtclient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+)
+
+// newReviewClient returns a client which decides the reviews by allow, the
+// reviewed attributes are appended to reviewed.
+func newReviewClient(allow func(*authorizationv1.ResourceAttributes) bool, reviewed *[]authorizationv1.ResourceAttributes) rtclient.Client {
+	return fake.NewClientBuilder().WithScheme(scheme.Scheme).WithInterceptorFuncs(interceptor.Funcs{
+		Create: func(ctx context.Context, client rtclient.WithWatch, obj rtclient.Object, opts ...rtclient.CreateOption) error {
+			review := obj.(*authorizationv1.SubjectAccessReview)
+			*reviewed = append(*reviewed, *review.Spec.ResourceAttributes)
+			review.Status.Allowed = allow(review.Spec.ResourceAttributes)
+			return nil
+		},
+	}).Build()
+}
+
+func TestAuthorize(t *testing.T) {
+	const prefix = "/kapis/gatewayapi.kubesphere.io/v1alpha1"
+	tests := []struct {
+		name   string
+		method string
+		route  string
+		path   string
+		// allowed are the namespaces in which the user may do anything, "" is
+		// the cluster scope
+		allowed  []string
+		wantCode int
+		want     authorizationv1.ResourceAttributes
+	}{
+		{
+			name:     "namespace scope",
+			method:   http.MethodGet,
+			route:    "/namespaces/:namespace/gateways/:gateway",
+			path:     "/namespaces/demo/gateways/gw",
+			allowed:  []string{"demo"},
+			wantCode: http.StatusOK,
+			want:     authorizationv1.ResourceAttributes{Namespace: "demo", Verb: "get", Group: "gateway.networking.k8s.io", Resource: "gateways", Name: "gw"},
+		},
+		{
+			name:     "other namespace",
+			method:   http.MethodDelete,
+			route:    "/namespaces/:namespace/gateways/:gateway",
+			path:     "/namespaces/demo/gateways/gw",
+			allowed:  []string{"other"},
+			wantCode: http.StatusForbidden,
+			want:     authorizationv1.ResourceAttributes{Namespace: "demo", Verb: "delete", Group: "gateway.networking.k8s.io", Resource: "gateways", Name: "gw"},
+		},
+		{
+			name:     "workspace scope is reviewed in cluster scope",
+			method:   http.MethodPost,
+			route:    "/workspaces/:workspace/gateways",
+			path:     "/workspaces/ws/gateways",
+			allowed:  []string{"demo"},
+			wantCode: http.StatusForbidden,
+			want:     authorizationv1.ResourceAttributes{Verb: "create", Group: "gateway.networking.k8s.io", Resource: "gateways"},
+		},
+		{
+			name:     "restore of revision",
+			method:   http.MethodPost,
+			route:    "/namespaces/:namespace/gateways/:gateway/revisions/:revision/restore",
+			path:     "/namespaces/demo/gateways/gw/revisions/2/restore",
+			allowed:  []string{"demo"},
+			wantCode: http.StatusOK,
+			want:     authorizationv1.ResourceAttributes{Namespace: "demo", Verb: "update", Group: "gateway.networking.k8s.io", Resource: "gateways", Subresource: "revisions", Name: "gw"},
+		},
+		{
+			name:     "cluster list of filtered resource",
+			method:   http.MethodGet,
+			route:    "/gateways",
+			path:     "/gateways",
+			wantCode: http.StatusOK,
+			want:     authorizationv1.ResourceAttributes{Verb: "list", Group: "gateway.networking.k8s.io", Resource: "gateways"},
+		},
+		{
+			name:     "cluster list of other resource",
+			method:   http.MethodGet,
+			route:    "/gatewayclasses",
+			path:     "/gatewayclasses",
+			wantCode: http.StatusForbidden,
+			want:     authorizationv1.ResourceAttributes{Verb: "list", Group: "gateway.networking.k8s.io", Resource: "gatewayclasses"},
+		},
+	}
+	gin.SetMode(gin.TestMode)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var reviewed []authorizationv1.ResourceAttributes
+			client := newReviewClient(func(attrs *authorizationv1.ResourceAttributes) bool {
+				for _, namespace := range tt.allowed {
+					if attrs.Namespace == namespace {
+						return true
+					}
+				}
+				return false
+			}, &reviewed)
+
+			engine := gin.New()
+			engine.Use(func(c *gin.Context) {
+				c.Request = c.Request.WithContext(request.WithUser(c.Request.Context(), &authenticationv1.UserInfo{Username: "alice"}))
+			}, Authorize(NewAuthorizer(client), "gateways"))
+			engine.Handle(tt.method, prefix+tt.route, func(c *gin.Context) { c.Status(http.StatusOK) })
+
+			recorder := httptest.NewRecorder()
+			engine.ServeHTTP(recorder, httptest.NewRequest(tt.method, prefix+tt.path, nil))
+			if recorder.Code != tt.wantCode {
+				t.Errorf("code = %d, want %d: %s", recorder.Code, tt.wantCode, recorder.Body.String())
+			}
+			if len(reviewed) != 1 || !reflect.DeepEqual(reviewed[0], tt.want) {
+				t.Errorf("reviewed = %+v, want %+v", reviewed, tt.want)
+			}
+		})
+	}
+}
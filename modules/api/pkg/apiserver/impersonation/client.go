@@ -0,0 +1,187 @@
+package impersonation
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/kubesphere-extensions/gateway-api/pkg/apiserver/request"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/transport"
+	rtclient "sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+type Mode string
+
+const (
+	// ModeServiceAccount does all requests as the service account of apiserver.
+	ModeServiceAccount Mode = "serviceaccount"
+	// ModeImpersonate does the writes as the caller by impersonation headers.
+	ModeImpersonate Mode = "impersonate"
+	// ModeToken does the writes with the bearer token of caller, the callers
+	// without token are impersonated.
+	ModeToken Mode = "token"
+)
+
+func (m Mode) Validate() error {
+	switch m {
+	case ModeServiceAccount, ModeImpersonate, ModeToken:
+		return nil
+	default:
+		return fmt.Errorf("invalid client mode %q, must be one of %s, %s and %s", m, ModeServiceAccount, ModeImpersonate, ModeToken)
+	}
+}
+
+// client reads from the shared client, which is backed by the informer cache
+// and authorized by the SubjectAccessReviews of requests, and does the writes
+// as the caller carried by the request context, so that Kubernetes RBAC and
+// audit apply to the caller.
+type client struct {
+	rtclient.Client
+
+	config *rest.Config
+	mode   Mode
+	// transport carries the credentials of apiserver, which impersonates the
+	// caller on it
+	transport http.RoundTripper
+	// anonymous only verifies the Kubernetes apiserver, which the bearer token
+	// of caller is sent on
+	anonymous http.RoundTripper
+}
+
+// NewClient returns a client which does the writes as the caller in mode, the
+// shared client is returned as is in ModeServiceAccount.
+func NewClient(config *rest.Config, shared rtclient.Client, mode Mode) (rtclient.Client, error) {
+	if err := mode.Validate(); err != nil {
+		return nil, err
+	}
+	if mode == ModeServiceAccount {
+		return shared, nil
+	}
+	// the transports are shared by the writes of all callers, so that the
+	// connections to the Kubernetes apiserver are reused
+	transport, err := rest.TransportFor(config)
+	if err != nil {
+		return nil, err
+	}
+	anonymous, err := rest.TransportFor(rest.AnonymousClientConfig(config))
+	if err != nil {
+		return nil, err
+	}
+	return &client{Client: shared, config: config, mode: mode, transport: transport, anonymous: anonymous}, nil
+}
+
+func (c *client) Create(ctx context.Context, obj rtclient.Object, opts ...rtclient.CreateOption) error {
+	writer, err := c.writer(ctx)
+	if err != nil {
+		return err
+	}
+	return writer.Create(ctx, obj, opts...)
+}
+
+func (c *client) Delete(ctx context.Context, obj rtclient.Object, opts ...rtclient.DeleteOption) error {
+	writer, err := c.writer(ctx)
+	if err != nil {
+		return err
+	}
+	return writer.Delete(ctx, obj, opts...)
+}
+
+func (c *client) Update(ctx context.Context, obj rtclient.Object, opts ...rtclient.UpdateOption) error {
+	writer, err := c.writer(ctx)
+	if err != nil {
+		return err
+	}
+	return writer.Update(ctx, obj, opts...)
+}
+
+func (c *client) Patch(ctx context.Context, obj rtclient.Object, patch rtclient.Patch, opts ...rtclient.PatchOption) error {
+	writer, err := c.writer(ctx)
+	if err != nil {
+		return err
+	}
+	return writer.Patch(ctx, obj, patch, opts...)
+}
+
+func (c *client) DeleteAllOf(ctx context.Context, obj rtclient.Object, opts ...rtclient.DeleteAllOfOption) error {
+	writer, err := c.writer(ctx)
+	if err != nil {
+		return err
+	}
+	return writer.DeleteAllOf(ctx, obj, opts...)
+}
+
+func (c *client) Status() rtclient.SubResourceWriter {
+	return c.SubResource("status")
+}
+
+func (c *client) SubResource(subResource string) rtclient.SubResourceClient {
+	return &subResourceClient{SubResourceReader: c.Client.SubResource(subResource), client: c, subResource: subResource}
+}
+
+// subResourceClient reads the subresource from the shared client, and does
+// the writes of it as the caller.
+type subResourceClient struct {
+	rtclient.SubResourceReader
+
+	client      *client
+	subResource string
+}
+
+func (c *subResourceClient) Create(ctx context.Context, obj rtclient.Object, subResource rtclient.Object, opts ...rtclient.SubResourceCreateOption) error {
+	writer, err := c.client.writer(ctx)
+	if err != nil {
+		return err
+	}
+	return writer.SubResource(c.subResource).Create(ctx, obj, subResource, opts...)
+}
+
+func (c *subResourceClient) Update(ctx context.Context, obj rtclient.Object, opts ...rtclient.SubResourceUpdateOption) error {
+	writer, err := c.client.writer(ctx)
+	if err != nil {
+		return err
+	}
+	return writer.SubResource(c.subResource).Update(ctx, obj, opts...)
+}
+
+func (c *subResourceClient) Patch(ctx context.Context, obj rtclient.Object, patch rtclient.Patch, opts ...rtclient.SubResourcePatchOption) error {
+	writer, err := c.client.writer(ctx)
+	if err != nil {
+		return err
+	}
+	return writer.SubResource(c.subResource).Patch(ctx, obj, patch, opts...)
+}
+
+// writer returns a client which talks to the Kubernetes apiserver as the
+// caller authenticated by the request. The bearer token is only carried by the
+// requests whose caller is authenticated by it.
+func (c *client) writer(ctx context.Context) (rtclient.Client, error) {
+	user, ok := request.UserFrom(ctx)
+	if !ok || user.Username == "" {
+		return nil, errors.NewUnauthorized("the caller of request is unknown")
+	}
+
+	var roundTripper http.RoundTripper
+	if token, ok := request.TokenFrom(ctx); ok && c.mode == ModeToken {
+		// drop the credentials of apiserver, only the token of caller is used
+		roundTripper = transport.NewBearerAuthRoundTripper(token, c.anonymous)
+	} else {
+		extra := make(map[string][]string, len(user.Extra))
+		for k, v := range user.Extra {
+			extra[k] = v
+		}
+		roundTripper = transport.NewImpersonatingRoundTripper(transport.ImpersonationConfig{
+			UserName: user.Username,
+			UID:      user.UID,
+			Groups:   user.Groups,
+			Extra:    extra,
+		}, c.transport)
+	}
+
+	return rtclient.New(c.config, rtclient.Options{
+		HTTPClient: &http.Client{Transport: roundTripper, Timeout: c.config.Timeout},
+		Scheme:     c.Client.Scheme(),
+		Mapper:     c.Client.RESTMapper(),
+	})
+}
@@ -0,0 +1,142 @@
+package query
+
+import (
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	ParameterName          = "name"
+	ParameterLabelSelector = "labelSelector"
+	ParameterPage          = "page"
+	ParameterLimit         = "limit"
+	ParameterOrderBy       = "sortBy"
+	ParameterAscending     = "ascending"
+)
+
+const (
+	FieldName              = "name"
+	FieldCreationTimeStamp = "creationTimestamp"
+)
+
+// Query represents api search terms
+type Query struct {
+	Pagination *Pagination
+
+	// sort result in which field, default to FieldCreationTimeStamp
+	SortBy string
+
+	// sort result in ascending or descending order, default to descending
+	Ascending bool
+
+	// filters of the result, keyed by query parameter name
+	Filters map[string]string
+
+	// label selector of the result
+	LabelSelector string
+}
+
+type Pagination struct {
+	// items per page
+	Limit int
+
+	// offset
+	Offset int
+}
+
+// NoPagination returns all items
+var NoPagination = newPagination(-1, 0)
+
+func newPagination(limit int, offset int) *Pagination {
+	return &Pagination{
+		Limit:  limit,
+		Offset: offset,
+	}
+}
+
+// GetValidPagination returns the start and end index of a page of total items
+func (p *Pagination) GetValidPagination(total int) (startIndex, endIndex int) {
+	// no pagination
+	if p.Limit == NoPagination.Limit {
+		return 0, total
+	}
+
+	// out of range
+	if p.Limit < 0 || p.Offset < 0 || p.Offset > total {
+		return 0, 0
+	}
+
+	startIndex = p.Offset
+	endIndex = startIndex + p.Limit
+
+	if endIndex > total {
+		endIndex = total
+	}
+
+	return startIndex, endIndex
+}
+
+func New() *Query {
+	return &Query{
+		Pagination: NoPagination,
+		SortBy:     FieldCreationTimeStamp,
+		Ascending:  false,
+		Filters:    map[string]string{},
+	}
+}
+
+// ParseQueryParameter parses the search terms of request, the parameters
+// other than pagination, sorting and label selector are kept as filters.
+func ParseQueryParameter(c *gin.Context) *Query {
+	q := New()
+
+	limit, err := strconv.Atoi(c.Query(ParameterLimit))
+	// equivalent to undefined, use the default value
+	if err != nil {
+		limit = -1
+	}
+	page, err := strconv.Atoi(c.Query(ParameterPage))
+	// equivalent to undefined, use the default value
+	if err != nil {
+		page = 1
+	}
+	q.Pagination = newPagination(limit, (page-1)*limit)
+
+	if sortBy := c.Query(ParameterOrderBy); sortBy != "" {
+		q.SortBy = sortBy
+	}
+	q.Ascending, _ = strconv.ParseBool(c.Query(ParameterAscending))
+	q.LabelSelector = c.Query(ParameterLabelSelector)
+
+	for key, values := range c.Request.URL.Query() {
+		switch key {
+		case ParameterPage, ParameterLimit, ParameterOrderBy, ParameterAscending, ParameterLabelSelector:
+			continue
+		}
+		if len(values) > 0 {
+			q.Filters[key] = values[0]
+		}
+	}
+
+	return q
+}
+
+// Compare reports whether left should be listed before right by the sorting
+// terms of query, objects with the same sort key are ordered by name.
+func (q *Query) Compare(left, right metav1.Object) bool {
+	if !q.Ascending {
+		left, right = right, left
+	}
+	switch q.SortBy {
+	case FieldName:
+		return left.GetName() < right.GetName()
+	default:
+		leftTime, rightTime := left.GetCreationTimestamp(), right.GetCreationTimestamp()
+		if leftTime.Equal(&rightTime) {
+			return left.GetName() < right.GetName()
+		}
+		return leftTime.Before(&rightTime)
+	}
+}
@@ -0,0 +1,36 @@
+package request
+
+import (
+	"context"
+
+	authenticationv1 "k8s.io/api/authentication/v1"
+)
+
+type key int
+
+const (
+	userKey key = iota
+	tokenKey
+)
+
+// WithUser returns a copy of ctx which carries the authenticated user.
+func WithUser(ctx context.Context, user *authenticationv1.UserInfo) context.Context {
+	return context.WithValue(ctx, userKey, user)
+}
+
+// UserFrom returns the authenticated user carried by ctx.
+func UserFrom(ctx context.Context) (*authenticationv1.UserInfo, bool) {
+	user, ok := ctx.Value(userKey).(*authenticationv1.UserInfo)
+	return user, ok
+}
+
+// WithToken returns a copy of ctx which carries the bearer token of caller.
+func WithToken(ctx context.Context, token string) context.Context {
+	return context.WithValue(ctx, tokenKey, token)
+}
+
+// TokenFrom returns the bearer token of caller carried by ctx.
+func TokenFrom(ctx context.Context) (string, bool) {
+	token, ok := ctx.Value(tokenKey).(string)
+	return token, ok && token != ""
+}
@@ -0,0 +1,133 @@
+package request
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	VerbGet    = "get"
+	VerbList   = "list"
+	VerbWatch  = "watch"
+	VerbCreate = "create"
+	VerbUpdate = "update"
+	VerbPatch  = "patch"
+	VerbDelete = "delete"
+
+	pathWorkspaces = "workspaces"
+	pathNamespaces = "namespaces"
+)
+
+// Attributes describes what a request does, which is resolved from the
+// registered path of gin route, e.g.
+//
+//	/kapis/gatewayapi.kubesphere.io/v1alpha1/namespaces/:namespace/gateways/:gateway/routes
+//
+// is resolved to the routes subresource of gateways in the namespace.
+type Attributes struct {
+	Verb string
+	// Group is the API group of resource, defaults to the group of Gateway API
+	Group       string
+	Workspace   string
+	Namespace   string
+	Resource    string
+	Subresource string
+	Name        string
+}
+
+// action is the verb and subresource which a request is authorized as.
+type action struct {
+	verb        string
+	subresource string
+}
+
+// actions are the actions of the paths whose verb or subresource differs from
+// the method and the segment after the resource, keyed by the method and the
+// fixed segments after the resource and its name, so that the path parameters
+// such as revision never appear in the subresource.
+var actions = map[string]action{
+	// creating a gateway from template is creating the gateway
+	http.MethodPost + " from-template":     {verb: VerbCreate},
+	http.MethodPost + " revisions/restore": {verb: VerbUpdate, subresource: "revisions"},
+	// validating a gateway is validating an update of it
+	http.MethodPost + " validate":     {verb: VerbUpdate},
+	http.MethodPost + " certificates": {verb: VerbUpdate, subresource: "certificates"},
+	// switching backends and rolling back rewrite the rules of route
+	http.MethodPost + " switch":   {verb: VerbUpdate},
+	http.MethodPost + " rollback": {verb: VerbUpdate},
+}
+
+// NewAttributes resolves the attributes of request, the path of the route is
+// expected to be /kapis/{group}/{version}/...
+func NewAttributes(c *gin.Context) *Attributes {
+	attrs := &Attributes{}
+	segments := strings.Split(strings.Trim(c.FullPath(), "/"), "/")
+	if len(segments) < 3 {
+		return attrs
+	}
+	segments = segments[3:]
+
+	for len(segments) >= 2 && strings.HasPrefix(segments[1], ":") {
+		if segments[0] == pathWorkspaces {
+			attrs.Workspace = c.Param(segments[1][1:])
+		} else if segments[0] == pathNamespaces {
+			attrs.Namespace = c.Param(segments[1][1:])
+		} else {
+			break
+		}
+		segments = segments[2:]
+	}
+
+	if len(segments) > 0 {
+		attrs.Resource = segments[0]
+		segments = segments[1:]
+	}
+	if len(segments) > 0 && strings.HasPrefix(segments[0], ":") {
+		attrs.Name = c.Param(segments[0][1:])
+		segments = segments[1:]
+	}
+	fixed := make([]string, 0, len(segments))
+	for _, segment := range segments {
+		if !strings.HasPrefix(segment, ":") {
+			fixed = append(fixed, segment)
+		}
+	}
+
+	attrs.Verb = verb(c, attrs)
+	if action, ok := actions[c.Request.Method+" "+strings.Join(fixed, "/")]; ok {
+		attrs.Verb = action.verb
+		attrs.Subresource = action.subresource
+	} else if len(fixed) > 0 {
+		attrs.Subresource = fixed[0]
+	}
+	return attrs
+}
+
+func verb(c *gin.Context, attrs *Attributes) string {
+	switch c.Request.Method {
+	case http.MethodPost:
+		return VerbCreate
+	case http.MethodPut:
+		return VerbUpdate
+	case http.MethodPatch:
+		return VerbPatch
+	case http.MethodDelete:
+		return VerbDelete
+	default:
+		if attrs.Name != "" {
+			return VerbGet
+		}
+		if watch, _ := strconv.ParseBool(c.Query("watch")); watch {
+			return VerbWatch
+		}
+		return VerbList
+	}
+}
+
+// ClusterScoped reports whether the request is neither workspace nor namespace scoped.
+func (a *Attributes) ClusterScoped() bool {
+	return a.Workspace == "" && a.Namespace == ""
+}
@@ -0,0 +1,110 @@
+package request
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestNewAttributes(t *testing.T) {
+	const prefix = "/kapis/gatewayapi.kubesphere.io/v1alpha1"
+	tests := []struct {
+		method string
+		route  string
+		path   string
+		want   Attributes
+	}{
+		{
+			method: http.MethodGet,
+			route:  "/gateways",
+			path:   "/gateways",
+			want:   Attributes{Verb: VerbList, Resource: "gateways"},
+		},
+		{
+			method: http.MethodGet,
+			route:  "/gateways",
+			path:   "/gateways?watch=true",
+			want:   Attributes{Verb: VerbWatch, Resource: "gateways"},
+		},
+		{
+			method: http.MethodGet,
+			route:  "/workspaces/:workspace/gateways/:gateway",
+			path:   "/workspaces/ws/gateways/gw",
+			want:   Attributes{Verb: VerbGet, Workspace: "ws", Resource: "gateways", Name: "gw"},
+		},
+		{
+			method: http.MethodPatch,
+			route:  "/namespaces/:namespace/gateways/:gateway",
+			path:   "/namespaces/demo/gateways/gw",
+			want:   Attributes{Verb: VerbPatch, Namespace: "demo", Resource: "gateways", Name: "gw"},
+		},
+		{
+			method: http.MethodGet,
+			route:  "/namespaces/:namespace/gateways/:gateway/status",
+			path:   "/namespaces/demo/gateways/gw/status",
+			want:   Attributes{Verb: VerbGet, Namespace: "demo", Resource: "gateways", Subresource: "status", Name: "gw"},
+		},
+		{
+			method: http.MethodPost,
+			route:  "/namespaces/:namespace/gateways/from-template",
+			path:   "/namespaces/demo/gateways/from-template",
+			want:   Attributes{Verb: VerbCreate, Namespace: "demo", Resource: "gateways"},
+		},
+		{
+			method: http.MethodPost,
+			route:  "/namespaces/:namespace/gateways/:gateway/revisions/:revision/restore",
+			path:   "/namespaces/demo/gateways/gw/revisions/3/restore",
+			want:   Attributes{Verb: VerbUpdate, Namespace: "demo", Resource: "gateways", Subresource: "revisions", Name: "gw"},
+		},
+		{
+			method: http.MethodGet,
+			route:  "/namespaces/:namespace/gateways/:gateway/revisions",
+			path:   "/namespaces/demo/gateways/gw/revisions",
+			want:   Attributes{Verb: VerbGet, Namespace: "demo", Resource: "gateways", Subresource: "revisions", Name: "gw"},
+		},
+		{
+			method: http.MethodPost,
+			route:  "/gateways/:gateway/validate",
+			path:   "/gateways/gw/validate",
+			want:   Attributes{Verb: VerbUpdate, Resource: "gateways", Name: "gw"},
+		},
+		{
+			method: http.MethodPost,
+			route:  "/gateways/:gateway/certificates",
+			path:   "/gateways/gw/certificates",
+			want:   Attributes{Verb: VerbUpdate, Resource: "gateways", Subresource: "certificates", Name: "gw"},
+		},
+		{
+			method: http.MethodPost,
+			route:  "/namespaces/:namespace/httproutes/:route/switch",
+			path:   "/namespaces/demo/httproutes/web/switch",
+			want:   Attributes{Verb: VerbUpdate, Namespace: "demo", Resource: "httproutes", Name: "web"},
+		},
+		{
+			method: http.MethodDelete,
+			route:  "/namespaces/:namespace/httproutes/:route/canary",
+			path:   "/namespaces/demo/httproutes/web/canary",
+			want:   Attributes{Verb: VerbDelete, Namespace: "demo", Resource: "httproutes", Subresource: "canary", Name: "web"},
+		},
+	}
+	gin.SetMode(gin.TestMode)
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.route, func(t *testing.T) {
+			var got *Attributes
+			engine := gin.New()
+			engine.Handle(tt.method, prefix+tt.route, func(c *gin.Context) {
+				got = NewAttributes(c)
+			})
+			engine.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(tt.method, prefix+tt.path, nil))
+			if got == nil {
+				t.Fatalf("route %s is not served", tt.route)
+			}
+			if !reflect.DeepEqual(*got, tt.want) {
+				t.Errorf("NewAttributes() = %+v, want %+v", *got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,229 @@
+package watch
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/watch"
+	toolscache "k8s.io/client-go/tools/cache"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// historySize is the number of recent events kept to resume watches from.
+	historySize = 1024
+	// watcherBufferSize is the number of events buffered for a watcher, the
+	// watcher which falls behind further is stopped.
+	watcherBufferSize = 256
+)
+
+// Event is an event of an object, ResourceVersion orders the events.
+type Event struct {
+	Type            watch.EventType
+	Object          client.Object
+	ResourceVersion uint64
+}
+
+// change is a change of an object observed by the informer, old is nil for
+// added objects and new is nil for deleted objects.
+type change struct {
+	old, new        client.Object
+	resourceVersion uint64
+}
+
+// Broadcaster fans out the events of a shared informer to the watchers, so all
+// watches of a kind are served by a single watch of the Kubernetes apiserver.
+type Broadcaster struct {
+	informers cache.Informers
+	newObject func() client.Object
+
+	startMu      sync.Mutex
+	registration toolscache.ResourceEventHandlerRegistration
+	// store is the store of informer, which the existing objects are listed
+	// from
+	store toolscache.Store
+
+	mu      sync.Mutex
+	history []change
+	// floor is the resource version from which the watches can be resumed
+	floor    uint64
+	latest   uint64
+	watchers map[*Watcher]struct{}
+}
+
+func NewBroadcaster(informers cache.Informers, newObject func() client.Object) *Broadcaster {
+	return &Broadcaster{
+		informers: informers,
+		newObject: newObject,
+		watchers:  map[*Watcher]struct{}{},
+	}
+}
+
+// ResourceVersion returns the latest resource version observed.
+func (b *Broadcaster) ResourceVersion() uint64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.latest
+}
+
+// Watch starts a watch of the objects which the filter accepts. All existing
+// objects are sent as ADDED events if resourceVersion is empty or "0",
+// otherwise the events after resourceVersion are sent, and an Expired error is
+// returned if the events are no longer kept.
+func (b *Broadcaster) Watch(ctx context.Context, resourceVersion string, filter func(client.Object) (bool, error)) (*Watcher, error) {
+	var since uint64
+	if resourceVersion != "" {
+		var err error
+		since, err = strconv.ParseUint(resourceVersion, 10, 64)
+		if err != nil {
+			return nil, errors.NewBadRequest(fmt.Sprintf("invalid resourceVersion %q", resourceVersion))
+		}
+	}
+	if err := b.start(ctx); err != nil {
+		return nil, err
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if since != 0 && since < b.floor {
+		return nil, errors.NewResourceExpired(fmt.Sprintf("too old resource version: %d (%d)", since, b.floor))
+	}
+
+	w := newWatcher(ctx, b, filter)
+	if since == 0 {
+		// the store is updated before the events are handled, so an event
+		// racing with the listing may be sent after the object it changes
+		for _, obj := range b.store.List() {
+			if object, ok := obj.(client.Object); ok {
+				w.pending = append(w.pending, change{new: object, resourceVersion: resourceVersionOf(object)})
+			}
+		}
+	} else {
+		for _, c := range b.history {
+			if c.resourceVersion > since {
+				w.pending = append(w.pending, c)
+			}
+		}
+	}
+	b.watchers[w] = struct{}{}
+	go w.run()
+	return w, nil
+}
+
+// start registers the event handler to the shared informer once, and waits
+// for the informer to be synced.
+func (b *Broadcaster) start(ctx context.Context) error {
+	// the informer may deliver the events while the handler is being added,
+	// so the registration is not guarded by the lock of events.
+	b.startMu.Lock()
+	if b.registration == nil {
+		informer, err := b.informers.GetInformer(ctx, b.newObject())
+		if err == nil {
+			sharedInformer, ok := informer.(toolscache.SharedInformer)
+			if !ok {
+				b.startMu.Unlock()
+				return fmt.Errorf("the informer of %T does not provide its store", b.newObject())
+			}
+			b.store = sharedInformer.GetStore()
+			b.registration, err = informer.AddEventHandler(toolscache.ResourceEventHandlerDetailedFuncs{
+				AddFunc:    b.onAdd,
+				UpdateFunc: b.onUpdate,
+				DeleteFunc: b.onDelete,
+			})
+		}
+		if err != nil {
+			b.startMu.Unlock()
+			return err
+		}
+	}
+	registration := b.registration
+	b.startMu.Unlock()
+
+	if !toolscache.WaitForCacheSync(ctx.Done(), registration.HasSynced) {
+		return errors.NewServiceUnavailable("the cache is not synced")
+	}
+	return nil
+}
+
+func (b *Broadcaster) onAdd(obj interface{}, isInInitialList bool) {
+	object, ok := obj.(client.Object)
+	if !ok {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	rv := b.observe(object)
+	if isInInitialList {
+		// the events before the initial list are unknown
+		b.floor = b.latest
+		return
+	}
+	b.broadcast(change{new: object, resourceVersion: rv})
+}
+
+func (b *Broadcaster) onUpdate(oldObj, newObj interface{}) {
+	old, ok := oldObj.(client.Object)
+	if !ok {
+		return
+	}
+	object, ok := newObj.(client.Object)
+	if !ok || old.GetResourceVersion() == object.GetResourceVersion() {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.broadcast(change{old: old, new: object, resourceVersion: b.observe(object)})
+}
+
+func (b *Broadcaster) onDelete(obj interface{}) {
+	if tombstone, ok := obj.(toolscache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
+	object, ok := obj.(client.Object)
+	if !ok {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	// the deleted object carries the resource version of deletion, or the last
+	// one known for the object deleted while the informer is disconnected.
+	b.broadcast(change{old: object, resourceVersion: b.observe(object)})
+}
+
+// observe updates the latest resource version by the object, and returns the
+// resource version of object.
+func (b *Broadcaster) observe(object client.Object) uint64 {
+	rv := resourceVersionOf(object)
+	if rv > b.latest {
+		b.latest = rv
+	}
+	return rv
+}
+
+func (b *Broadcaster) broadcast(c change) {
+	b.history = append(b.history, c)
+	if len(b.history) > historySize {
+		b.floor = b.history[0].resourceVersion
+		b.history = b.history[1:]
+	}
+	for w := range b.watchers {
+		if !w.send(c) {
+			delete(b.watchers, w)
+		}
+	}
+}
+
+func (b *Broadcaster) remove(w *Watcher) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	delete(b.watchers, w)
+}
+
+func resourceVersionOf(object client.Object) uint64 {
+	rv, _ := strconv.ParseUint(object.GetResourceVersion(), 10, 64)
+	return rv
+}
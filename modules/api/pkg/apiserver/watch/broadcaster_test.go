@@ -0,0 +1,121 @@
+package watch
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/watch"
+	toolscache "k8s.io/client-go/tools/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// syncedRegistration is the registration of a synced informer.
+type syncedRegistration struct{}
+
+func (syncedRegistration) HasSynced() bool { return true }
+
+// newTestBroadcaster returns a broadcaster which is already started, the
+// events are fed by calling its handlers.
+func newTestBroadcaster() *Broadcaster {
+	b := NewBroadcaster(nil, func() client.Object { return &corev1.ConfigMap{} })
+	b.registration = syncedRegistration{}
+	b.store = toolscache.NewStore(toolscache.MetaNamespaceKeyFunc)
+	return b
+}
+
+func newObject(name string, resourceVersion uint64) *corev1.ConfigMap {
+	return &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{
+		Namespace:       "demo",
+		Name:            name,
+		ResourceVersion: strconv.FormatUint(resourceVersion, 10),
+	}}
+}
+
+func TestBroadcasterResume(t *testing.T) {
+	b := newTestBroadcaster()
+	b.onAdd(newObject("a", 10), true)
+	b.onAdd(newObject("b", 11), false)
+	b.onUpdate(newObject("a", 10), newObject("a", 12))
+	b.onDelete(newObject("b", 13))
+	b.onAdd(newObject("c", 14), false)
+	b.onDelete(toolscache.DeletedFinalStateUnknown{Key: "demo/c", Obj: newObject("c", 15)})
+
+	type event struct {
+		Type            watch.EventType
+		Name            string
+		ResourceVersion uint64
+	}
+	tests := []struct {
+		name  string
+		since string
+		want  []event
+	}{
+		{
+			name:  "resume before deletion",
+			since: "11",
+			want: []event{
+				{watch.Modified, "a", 12},
+				{watch.Deleted, "b", 13},
+				{watch.Added, "c", 14},
+				{watch.Deleted, "c", 15},
+			},
+		},
+		{
+			name:  "resume from deletion",
+			since: "13",
+			want: []event{
+				{watch.Added, "c", 14},
+				{watch.Deleted, "c", 15},
+			},
+		},
+		{
+			name:  "resume from deletion of tombstone",
+			since: "15",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			w, err := b.Watch(ctx, tt.since, func(client.Object) (bool, error) { return true, nil })
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer w.Stop()
+
+			var got []event
+			for len(got) < len(tt.want) {
+				select {
+				case e := <-w.ResultChan():
+					got = append(got, event{e.Type, e.Object.GetName(), e.ResourceVersion})
+				case <-time.After(time.Second):
+					t.Fatalf("events = %v, want %v", got, tt.want)
+				}
+			}
+			select {
+			case e := <-w.ResultChan():
+				t.Fatalf("unexpected event %s %s at %d", e.Type, e.Object.GetName(), e.ResourceVersion)
+			case <-time.After(50 * time.Millisecond):
+			}
+			for i := range tt.want {
+				if got[i] != tt.want[i] {
+					t.Errorf("events = %v, want %v", got, tt.want)
+					break
+				}
+			}
+		})
+	}
+}
+
+func TestBroadcasterExpired(t *testing.T) {
+	b := newTestBroadcaster()
+	b.onAdd(newObject("a", 10), true)
+	_, err := b.Watch(context.Background(), "9", func(client.Object) (bool, error) { return true, nil })
+	if err == nil {
+		t.Fatal("Watch() from a resource version before the initial list is not expired")
+	}
+}
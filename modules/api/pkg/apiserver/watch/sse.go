@@ -0,0 +1,112 @@
+package watch
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// DefaultBookmarkInterval is the interval of bookmark events.
+	DefaultBookmarkInterval = 30 * time.Second
+
+	// headerLastEventID is sent by the EventSource of browsers when it
+	// reconnects, which is the resource version of the last event.
+	headerLastEventID = "Last-Event-ID"
+)
+
+// watchEvent is the data of events, which is the same as the events of
+// Kubernetes watch.
+type watchEvent struct {
+	Type   watch.EventType `json:"type"`
+	Object runtime.Object  `json:"object"`
+}
+
+// ResourceVersion returns the resource version to resume the watch from, the
+// Last-Event-ID header of a reconnecting EventSource takes precedence.
+func ResourceVersion(c *gin.Context) string {
+	if id := c.GetHeader(headerLastEventID); id != "" {
+		return id
+	}
+	return c.Query("resourceVersion")
+}
+
+// ServeSSE streams the events of watcher as Server-Sent Events until the
+// request is done or the watch stops. The id of events is the resource
+// version, and bookmarks with the latest resource version are sent every
+// interval. The reason of a stopped watch is sent as an ERROR event.
+func ServeSSE(c *gin.Context, w *Watcher, gvk schema.GroupVersionKind, interval time.Duration) {
+	defer w.Stop()
+
+	header := c.Writer.Header()
+	header.Set("Content-Type", "text/event-stream")
+	header.Set("Cache-Control", "no-cache")
+	header.Set("Connection", "keep-alive")
+	header.Set("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+	c.Writer.Flush()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-c.Request.Context().Done():
+			return
+		case <-ticker.C:
+			bookmark := &metav1.PartialObjectMetadata{}
+			bookmark.SetGroupVersionKind(gvk)
+			rv := w.broadcaster.ResourceVersion()
+			bookmark.SetResourceVersion(strconv.FormatUint(rv, 10))
+			if !writeEvent(c, rv, watch.Bookmark, bookmark) {
+				return
+			}
+		case event, ok := <-w.ResultChan():
+			if !ok {
+				if err := w.Err(); err != nil {
+					status := errors.NewInternalError(err).Status()
+					if apiStatus, ok := err.(errors.APIStatus); ok {
+						status = apiStatus.Status()
+					}
+					status.TypeMeta = metav1.TypeMeta{Kind: "Status", APIVersion: "v1"}
+					writeEvent(c, 0, watch.Error, &status)
+				}
+				return
+			}
+			object := event.Object.DeepCopyObject().(client.Object)
+			object.GetObjectKind().SetGroupVersionKind(gvk)
+			if !writeEvent(c, event.ResourceVersion, event.Type, object) {
+				return
+			}
+		}
+	}
+}
+
+func writeEvent(c *gin.Context, resourceVersion uint64, eventType watch.EventType, object runtime.Object) bool {
+	data, err := json.Marshal(watchEvent{Type: eventType, Object: object})
+	if err != nil {
+		klog.Errorf("marshal watch event: %v", err)
+		return false
+	}
+	if resourceVersion != 0 {
+		_, err = fmt.Fprintf(c.Writer, "id: %d\n", resourceVersion)
+	}
+	if err == nil {
+		_, err = fmt.Fprintf(c.Writer, "event: %s\ndata: %s\n\n", eventType, data)
+	}
+	if err != nil {
+		return false
+	}
+	c.Writer.Flush()
+	return true
+}
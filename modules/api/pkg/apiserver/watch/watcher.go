@@ -0,0 +1,137 @@
+package watch
+
+import (
+	"context"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/watch"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Watcher receives the events of the objects which its filter accepts, an
+// object which stops being accepted is sent as DELETED.
+type Watcher struct {
+	ctx         context.Context
+	cancel      context.CancelFunc
+	broadcaster *Broadcaster
+	filter      func(client.Object) (bool, error)
+
+	// pending are the changes before the watcher is registered
+	pending []change
+	changes chan change
+	// stopped is guarded by the lock of broadcaster
+	stopped bool
+
+	result chan Event
+	err    error
+}
+
+func newWatcher(ctx context.Context, broadcaster *Broadcaster, filter func(client.Object) (bool, error)) *Watcher {
+	ctx, cancel := context.WithCancel(ctx)
+	return &Watcher{
+		ctx:         ctx,
+		cancel:      cancel,
+		broadcaster: broadcaster,
+		filter:      filter,
+		changes:     make(chan change, watcherBufferSize),
+		result:      make(chan Event),
+	}
+}
+
+// ResultChan returns the channel of events, which is closed when the watch
+// stops.
+func (w *Watcher) ResultChan() <-chan Event {
+	return w.result
+}
+
+// Err returns the reason why the watch stopped, it is only valid after the
+// channel of events is closed.
+func (w *Watcher) Err() error {
+	return w.err
+}
+
+func (w *Watcher) Stop() {
+	w.cancel()
+	w.broadcaster.remove(w)
+}
+
+// send queues the change without blocking the broadcaster, the watcher is
+// stopped if it falls behind.
+func (w *Watcher) send(c change) bool {
+	if w.stopped {
+		return false
+	}
+	select {
+	case w.changes <- c:
+		return true
+	default:
+		w.stopped = true
+		close(w.changes)
+		return false
+	}
+}
+
+func (w *Watcher) run() {
+	defer close(w.result)
+	for _, c := range w.pending {
+		if !w.emit(c) {
+			return
+		}
+	}
+	w.pending = nil
+
+	for {
+		select {
+		case <-w.ctx.Done():
+			return
+		case c, ok := <-w.changes:
+			if !ok {
+				w.err = errors.NewTooManyRequests("the watch falls behind the events, resume it from the last resource version", 1)
+				return
+			}
+			if !w.emit(c) {
+				return
+			}
+		}
+	}
+}
+
+// emit sends the event of change to the result channel, and reports whether
+// the watcher should go on.
+func (w *Watcher) emit(c change) bool {
+	var oldMatched, newMatched bool
+	var err error
+	if c.old != nil {
+		if oldMatched, err = w.filter(c.old); err != nil {
+			w.err = err
+			return false
+		}
+	}
+	if c.new != nil {
+		if newMatched, err = w.filter(c.new); err != nil {
+			w.err = err
+			return false
+		}
+	}
+
+	event := Event{ResourceVersion: c.resourceVersion}
+	switch {
+	case oldMatched && newMatched:
+		event.Type, event.Object = watch.Modified, c.new
+	case newMatched:
+		event.Type, event.Object = watch.Added, c.new
+	case oldMatched && c.new != nil:
+		event.Type, event.Object = watch.Deleted, c.new
+	case oldMatched:
+		event.Type, event.Object = watch.Deleted, c.old
+	default:
+		return true
+	}
+
+	select {
+	case <-w.ctx.Done():
+		return false
+	case w.result <- event:
+		return true
+	}
+}
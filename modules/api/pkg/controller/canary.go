@@ -0,0 +1,73 @@
+package controller
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/kubesphere-extensions/gateway-api/pkg/utils/gatewayutil"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	apisv1 "sigs.k8s.io/gateway-api/apis/v1"
+)
+
+// CanaryReconciler advances the steps of the canary releases of HTTPRoutes
+// once the pause of each step has passed.
+type CanaryReconciler struct {
+	client client.Client
+	log    logr.Logger
+}
+
+func (r *CanaryReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	route := &apisv1.HTTPRoute{}
+	err := r.client.Get(ctx, req.NamespacedName, route)
+	if err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+	canary, err := gatewayutil.CanaryOf(route)
+	if err != nil {
+		// the annotation is validated by the API, an invalid one is left as is
+		r.log.Error(err, "skip canary", "namespace", route.Namespace, "name", route.Name)
+		return ctrl.Result{}, nil
+	}
+	if canary == nil {
+		return ctrl.Result{}, nil
+	}
+
+	changed, wait := canary.Advance(time.Now())
+	if changed {
+		err = gatewayutil.SetCanary(route, canary)
+		if err != nil {
+			// the rules are checked by the API, the ones which can not be
+			// rendered are left as is
+			r.log.Error(err, "skip canary", "namespace", route.Namespace, "name", route.Name)
+			return ctrl.Result{}, nil
+		}
+		// the update fails on conflict if the canary is changed meanwhile
+		err = r.client.Update(ctx, route)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+		r.log.V(4).Info("advance canary", "namespace", route.Namespace, "name", route.Name,
+			"phase", canary.Status.Phase, "step", canary.Status.Step, "weight", canary.Status.Weight)
+	}
+	if canary.Status.Phase != gatewayutil.CanaryProgressing {
+		return ctrl.Result{}, nil
+	}
+	return ctrl.Result{RequeueAfter: wait}, nil
+}
+
+func (r *CanaryReconciler) SetupWithManager(mgr manager.Manager) error {
+	r.client = mgr.GetClient()
+	r.log = mgr.GetLogger().WithName("canary-controller")
+	return ctrl.NewControllerManagedBy(mgr).
+		Named("canary").
+		For(&apisv1.HTTPRoute{}, builder.WithPredicates(predicate.NewPredicateFuncs(func(object client.Object) bool {
+			_, ok := object.GetAnnotations()[gatewayutil.AnnotationCanary]
+			return ok
+		}))).
+		Complete(r)
+}
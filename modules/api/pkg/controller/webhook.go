@@ -2,23 +2,73 @@ package controller
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
 
 	"github.com/go-logr/logr"
+	"github.com/kubesphere-extensions/gateway-api/pkg/utils/gatewayutil"
+	admissionv1 "k8s.io/api/admission/v1"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/manager"
 	"sigs.k8s.io/controller-runtime/pkg/webhook"
 	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+	apisv1 "sigs.k8s.io/gateway-api/apis/v1"
 )
 
+// GatewayWebhook defaults the AllowedRoutes of gateways and validates their
+// scope labels and listeners, it is registered as a mutating webhook so that
+// the defaulted gateway is the one being validated.
 type GatewayWebhook struct {
 	client  client.Client
 	decoder admission.Decoder
 	log     logr.Logger
 }
 
-func (w *GatewayWebhook) Handle(_ context.Context, req admission.Request) admission.Response {
-	// TODO implement me!
-	return admission.Response{}
+func (w *GatewayWebhook) Handle(ctx context.Context, req admission.Request) admission.Response {
+	if req.Operation != admissionv1.Create && req.Operation != admissionv1.Update {
+		return admission.Allowed("")
+	}
+
+	gateway := &apisv1.Gateway{}
+	err := w.decoder.Decode(req, gateway)
+	if err != nil {
+		return admission.Errored(http.StatusBadRequest, err)
+	}
+
+	var old *apisv1.Gateway
+	if req.Operation == admissionv1.Update {
+		old = &apisv1.Gateway{}
+		err = w.decoder.DecodeRaw(req.OldObject, old)
+		if err != nil {
+			return admission.Errored(http.StatusBadRequest, err)
+		}
+		for _, key := range gatewayutil.ScopeLabelKeys {
+			if old.Labels[key] != gateway.Labels[key] {
+				return admission.Denied(fmt.Sprintf("the label %s of gateway %s can not be changed", key, gateway.Name))
+			}
+		}
+	}
+
+	err = gatewayutil.DefaultAllowedRoutes(gateway)
+	if err != nil {
+		return admission.Denied(err.Error())
+	}
+
+	result, err := gatewayutil.ValidateGateway(ctx, w.client, gateway, old)
+	if err != nil {
+		return admission.Errored(http.StatusInternalServerError, err)
+	}
+	if len(result.Errors) != 0 {
+		w.log.V(4).Info("reject gateway", "namespace", gateway.Namespace, "name", gateway.Name, "reason", result.Errors.ToAggregate().Error())
+		return admission.Denied(result.Errors.ToAggregate().Error())
+	}
+
+	marshaled, err := json.Marshal(gateway)
+	if err != nil {
+		return admission.Errored(http.StatusInternalServerError, err)
+	}
+	return admission.PatchResponseFromRaw(req.Object.Raw, marshaled).WithWarnings(result.Warnings...)
 }
 
 func (w *GatewayWebhook) SetupWithWebhook(mgr manager.Manager) error {
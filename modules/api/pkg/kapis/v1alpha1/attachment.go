@@ -0,0 +1,176 @@
+package v1alpha1
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kubesphere-extensions/gateway-api/pkg/api"
+	"github.com/kubesphere-extensions/gateway-api/pkg/apiserver/query"
+	"github.com/kubesphere-extensions/gateway-api/pkg/utils/gatewayutil"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	rtclient "sigs.k8s.io/controller-runtime/pkg/client"
+	apisv1 "sigs.k8s.io/gateway-api/apis/v1"
+	"sigs.k8s.io/gateway-api/apis/v1alpha2"
+)
+
+// RouteAttachment is a parentRef of route which targets a gateway.
+type RouteAttachment struct {
+	APIVersion string `json:"apiVersion"`
+	Kind       string `json:"kind"`
+	Namespace  string `json:"namespace"`
+	Name       string `json:"name"`
+
+	SectionName *apisv1.SectionName `json:"sectionName,omitempty"`
+	Port        *apisv1.PortNumber  `json:"port,omitempty"`
+
+	// Listeners are the listeners of gateway which the parentRef selects and
+	// which can serve the kind of route
+	Listeners []ListenerAttachment `json:"listeners"`
+
+	// ControllerName and Conditions are reported by the RouteParentStatus of the parentRef
+	ControllerName apisv1.GatewayController `json:"controllerName,omitempty"`
+	Conditions     []metav1.Condition       `json:"conditions,omitempty"`
+}
+
+type ListenerAttachment struct {
+	Name      apisv1.SectionName `json:"name"`
+	Hostnames []apisv1.Hostname  `json:"hostnames,omitempty"`
+}
+
+// ListGatewayRoutes lists the routes of all kinds which target the gateway,
+// only the routes in the namespaces of request scope are listed.
+func (h *Handler) ListGatewayRoutes(c *gin.Context) {
+	params := handleRequestParams(c, resourceNameGateway)
+	gateway, err := h.getGateway(c.Request.Context(), params)
+	if err != nil {
+		api.HandleError(c, err)
+		return
+	}
+
+	attachments, err := h.listRouteAttachments(c.Request.Context(), params, gateway)
+	if err != nil {
+		api.HandleError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, api.ListResult{Items: attachments, TotalItems: len(attachments)})
+}
+
+func (h *Handler) listRouteAttachments(ctx context.Context, params ResourceParams, gateway *apisv1.Gateway) ([]RouteAttachment, error) {
+	attachments := make([]RouteAttachment, 0)
+	for _, kind := range routeKinds {
+		routes, err := NewRouteHandler(h.client, h.serviceClient, kind, nil).listRoutes(ctx, params, query.New())
+		if err != nil {
+			// the experimental routes may not be installed
+			if meta.IsNoMatchError(err) {
+				continue
+			}
+			return nil, err
+		}
+		for _, route := range routes {
+			attachments = append(attachments, kind.attachments(route, gateway)...)
+		}
+	}
+	return attachments, nil
+}
+
+// attachments returns the parentRefs of route which target the gateway.
+func (k routeKind) attachments(route rtclient.Object, gateway *apisv1.Gateway) []RouteAttachment {
+	attachments := make([]RouteAttachment, 0)
+	for _, ref := range k.spec(route).ParentRefs {
+		if !isGatewayRef(ref) || !refersTo(ref, route.GetNamespace(), gateway) {
+			continue
+		}
+
+		attachment := RouteAttachment{
+			APIVersion:  k.gvk.GroupVersion().String(),
+			Kind:        k.gvk.Kind,
+			Namespace:   route.GetNamespace(),
+			Name:        route.GetName(),
+			SectionName: ref.SectionName,
+			Port:        ref.Port,
+			Listeners:   make([]ListenerAttachment, 0),
+		}
+		for _, listener := range gateway.Spec.Listeners {
+			if !selectsListener(ref, listener) || !k.servesProtocol(listener.Protocol) || !k.allowedBy(listener.AllowedRoutes) {
+				continue
+			}
+			attachment.Listeners = append(attachment.Listeners, ListenerAttachment{
+				Name:      listener.Name,
+				Hostnames: gatewayutil.IntersectHostnames(listener.Hostname, routeHostnames(route)),
+			})
+		}
+		for _, parent := range routeStatus(route).Parents {
+			if sameParentRef(parent.ParentRef, ref, route.GetNamespace()) {
+				attachment.ControllerName = parent.ControllerName
+				attachment.Conditions = parent.Conditions
+				break
+			}
+		}
+		attachments = append(attachments, attachment)
+	}
+	return attachments
+}
+
+// routeHostnames returns the hostnames of route, nil for the kinds without
+// hostnames.
+func routeHostnames(route rtclient.Object) []apisv1.Hostname {
+	switch r := route.(type) {
+	case *apisv1.HTTPRoute:
+		return r.Spec.Hostnames
+	case *apisv1.GRPCRoute:
+		return r.Spec.Hostnames
+	case *v1alpha2.TLSRoute:
+		return r.Spec.Hostnames
+	default:
+		return nil
+	}
+}
+
+// routeStatus returns the status of route.
+func routeStatus(route rtclient.Object) *apisv1.RouteStatus {
+	switch r := route.(type) {
+	case *apisv1.HTTPRoute:
+		return &r.Status.RouteStatus
+	case *apisv1.GRPCRoute:
+		return &r.Status.RouteStatus
+	case *v1alpha2.TLSRoute:
+		return &r.Status.RouteStatus
+	case *v1alpha2.TCPRoute:
+		return &r.Status.RouteStatus
+	case *v1alpha2.UDPRoute:
+		return &r.Status.RouteStatus
+	default:
+		return &apisv1.RouteStatus{}
+	}
+}
+
+// refersTo reports whether the parentRef of a route in namespace refers to the gateway.
+func refersTo(ref apisv1.ParentReference, namespace string, gateway *apisv1.Gateway) bool {
+	if ref.Namespace != nil {
+		namespace = string(*ref.Namespace)
+	}
+	return namespace == gateway.Namespace && string(ref.Name) == gateway.Name
+}
+
+// sameParentRef reports whether two parentRefs of a route in namespace are the same.
+func sameParentRef(a, b apisv1.ParentReference, namespace string) bool {
+	if !isGatewayRef(a) || !isGatewayRef(b) || a.Name != b.Name {
+		return false
+	}
+	aNamespace, bNamespace := namespace, namespace
+	if a.Namespace != nil {
+		aNamespace = string(*a.Namespace)
+	}
+	if b.Namespace != nil {
+		bNamespace = string(*b.Namespace)
+	}
+	if aNamespace != bNamespace {
+		return false
+	}
+	if (a.SectionName == nil) != (b.SectionName == nil) || (a.SectionName != nil && *a.SectionName != *b.SectionName) {
+		return false
+	}
+	return (a.Port == nil) == (b.Port == nil) && (a.Port == nil || *a.Port == *b.Port)
+}
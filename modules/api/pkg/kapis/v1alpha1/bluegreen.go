@@ -0,0 +1,315 @@
+package v1alpha1
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kubesphere-extensions/gateway-api/pkg/api"
+	"github.com/kubesphere-extensions/gateway-api/pkg/utils/gatewayutil"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	rtclient "sigs.k8s.io/controller-runtime/pkg/client"
+	apisv1 "sigs.k8s.io/gateway-api/apis/v1"
+)
+
+// AnnotationPreviousSpec holds the spec of route in JSON before it is switched
+// or rolled back, which the rollback restores.
+const AnnotationPreviousSpec = "gatewayapi.kubesphere.io/previous-spec"
+
+// BackendSwitch switches the backendRefs of all rules of a route from one set
+// of backends to another.
+type BackendSwitch struct {
+	// From are the backends switched from, a backend without port matches all
+	// ports of it
+	From []apisv1.BackendObjectReference `json:"from"`
+	// To are the backends switched to, which replace the backends of From in
+	// every rule routing to any of them
+	To []apisv1.BackendRef `json:"to"`
+	// ResourceVersion is the version of route which the switch is based on,
+	// the switch fails with conflict if the route is changed since then
+	ResourceVersion string `json:"resourceVersion,omitempty"`
+}
+
+// Rollback restores the previous spec of route.
+type Rollback struct {
+	ResourceVersion string `json:"resourceVersion,omitempty"`
+}
+
+// SwitchBackends does a blue/green switch of route. The switch is written in
+// a single update of the route with the spec before it kept in annotation, so
+// that it can be rolled back at once.
+func (h *RouteHandler) SwitchBackends(c *gin.Context) {
+	params := handleRequestParams(c, resourceNameRoute)
+	ctx := c.Request.Context()
+	backendSwitch := &BackendSwitch{}
+	err := c.ShouldBindJSON(backendSwitch)
+	if err != nil {
+		api.HandleBadRequest(c, err)
+		return
+	}
+	var errs field.ErrorList
+	if len(backendSwitch.From) == 0 {
+		errs = append(errs, field.Required(field.NewPath("from"), ""))
+	}
+	if len(backendSwitch.To) == 0 {
+		errs = append(errs, field.Required(field.NewPath("to"), ""))
+	}
+	for i, ref := range backendSwitch.To {
+		if ref.Name == "" {
+			errs = append(errs, field.Required(field.NewPath("to").Index(i).Child("name"), ""))
+		}
+	}
+	if len(errs) != 0 {
+		api.HandleBadRequest(c, errs.ToAggregate())
+		return
+	}
+
+	route := h.kind.newObject()
+	err = h.client.Get(ctx, types.NamespacedName{Namespace: params.Namespace, Name: params.ResourceName}, route)
+	if err != nil {
+		api.HandleError(c, err)
+		return
+	}
+	err = h.kind.checkNoActiveCanary(route)
+	if err != nil {
+		api.HandleError(c, err)
+		return
+	}
+	previous, err := h.kind.marshalSpec(route)
+	if err != nil {
+		api.HandleError(c, err)
+		return
+	}
+
+	switched, err := h.kind.switchBackends(route, backendSwitch.From, backendSwitch.To)
+	if err != nil {
+		api.HandleError(c, err)
+		return
+	}
+	if switched == 0 {
+		api.HandleBadRequest(c, field.Invalid(field.NewPath("from"), backendSwitch.From, "no rule of route routes to the backends"))
+		return
+	}
+	h.writeRevision(c, route, previous, backendSwitch.ResourceVersion)
+}
+
+// RollbackRoute restores the spec of route before the last switch or
+// rollback, and keeps the current spec as the previous one, so a rollback can
+// be undone by another rollback.
+func (h *RouteHandler) RollbackRoute(c *gin.Context) {
+	params := handleRequestParams(c, resourceNameRoute)
+	ctx := c.Request.Context()
+	rollback := &Rollback{}
+	if c.Request.ContentLength != 0 {
+		err := c.ShouldBindJSON(rollback)
+		if err != nil {
+			api.HandleBadRequest(c, err)
+			return
+		}
+	}
+
+	route := h.kind.newObject()
+	err := h.client.Get(ctx, types.NamespacedName{Namespace: params.Namespace, Name: params.ResourceName}, route)
+	if err != nil {
+		api.HandleError(c, err)
+		return
+	}
+	err = h.kind.checkNoActiveCanary(route)
+	if err != nil {
+		api.HandleError(c, err)
+		return
+	}
+	spec, ok := route.GetAnnotations()[AnnotationPreviousSpec]
+	if !ok {
+		api.HandleBadRequest(c, fmt.Errorf("%s %s has no previous spec to roll back to", h.kind.gvk.Kind, route.GetName()))
+		return
+	}
+	previous, err := h.kind.marshalSpec(route)
+	if err != nil {
+		api.HandleError(c, err)
+		return
+	}
+	err = h.kind.unmarshalSpec(route, []byte(spec))
+	if err != nil {
+		api.HandleError(c, errors.NewInternalError(fmt.Errorf("invalid annotation %s: %v", AnnotationPreviousSpec, err)))
+		return
+	}
+	warnings, err := h.validateParentRefs(ctx, route)
+	addWarnings(c, warnings)
+	if err != nil {
+		api.HandleError(c, err)
+		return
+	}
+	h.writeRevision(c, route, previous, rollback.ResourceVersion)
+}
+
+// writeRevision updates the route with the previous spec kept in annotation.
+// The update is preconditioned on resourceVersion if it is set, otherwise on
+// the version which the route is read at.
+func (h *RouteHandler) writeRevision(c *gin.Context, route rtclient.Object, previous []byte, resourceVersion string) {
+	annotations := route.GetAnnotations()
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	annotations[AnnotationPreviousSpec] = string(previous)
+	route.SetAnnotations(annotations)
+	if resourceVersion != "" {
+		route.SetResourceVersion(resourceVersion)
+	}
+
+	err := h.client.Update(c.Request.Context(), route)
+	if err != nil {
+		if errors.IsConflict(err) {
+			api.HandleConflict(c, err)
+			return
+		}
+		api.HandleError(c, err)
+		return
+	}
+	h.revisions.record(c.Request.Context(), route, h.kind.gvk.Kind, OperationUpdate)
+	c.JSON(http.StatusOK, route)
+}
+
+// checkNoActiveCanary returns a conflict if the route has an active canary
+// release, whose rules would be overwritten.
+func (k routeKind) checkNoActiveCanary(route rtclient.Object) error {
+	httpRoute, ok := route.(*apisv1.HTTPRoute)
+	if !ok {
+		return nil
+	}
+	canary, err := gatewayutil.CanaryOf(httpRoute)
+	if err != nil {
+		return err
+	}
+	if canary != nil && canary.Active() {
+		return errors.NewConflict(k.gvk.GroupVersion().WithResource(k.resource).GroupResource(), route.GetName(),
+			fmt.Errorf("the active canary release must be aborted first"))
+	}
+	return nil
+}
+
+func (k routeKind) marshalSpec(route rtclient.Object) ([]byte, error) {
+	switch r := route.(type) {
+	case *apisv1.HTTPRoute:
+		return json.Marshal(r.Spec)
+	case *apisv1.GRPCRoute:
+		return json.Marshal(r.Spec)
+	default:
+		return nil, errors.NewMethodNotSupported(k.gvk.GroupVersion().WithResource(k.resource).GroupResource(), "switch")
+	}
+}
+
+func (k routeKind) unmarshalSpec(route rtclient.Object, data []byte) error {
+	switch r := route.(type) {
+	case *apisv1.HTTPRoute:
+		spec := apisv1.HTTPRouteSpec{}
+		if err := json.Unmarshal(data, &spec); err != nil {
+			return err
+		}
+		r.Spec = spec
+	case *apisv1.GRPCRoute:
+		spec := apisv1.GRPCRouteSpec{}
+		if err := json.Unmarshal(data, &spec); err != nil {
+			return err
+		}
+		r.Spec = spec
+	default:
+		return errors.NewMethodNotSupported(k.gvk.GroupVersion().WithResource(k.resource).GroupResource(), "rollback")
+	}
+	return nil
+}
+
+// switchBackends replaces the backendRefs to any backend of from by the
+// backendRefs of to in all rules of route, and returns the number of rules
+// switched. The filters of the first replaced backendRef of a rule are kept
+// by the backendRefs of to.
+func (k routeKind) switchBackends(route rtclient.Object, from []apisv1.BackendObjectReference, to []apisv1.BackendRef) (int, error) {
+	namespace := route.GetNamespace()
+	switched := 0
+	switch r := route.(type) {
+	case *apisv1.HTTPRoute:
+		for i := range r.Spec.Rules {
+			rule := &r.Spec.Rules[i]
+			refs := make([]apisv1.HTTPBackendRef, 0, len(rule.BackendRefs))
+			var filters []apisv1.HTTPRouteFilter
+			replaced := false
+			for _, ref := range rule.BackendRefs {
+				if !matchesBackend(namespace, ref.BackendObjectReference, from) {
+					refs = append(refs, ref)
+					continue
+				}
+				if !replaced {
+					filters = ref.Filters
+					replaced = true
+				}
+			}
+			if !replaced {
+				continue
+			}
+			for _, ref := range to {
+				refs = append(refs, apisv1.HTTPBackendRef{BackendRef: *ref.DeepCopy(), Filters: filters})
+			}
+			rule.BackendRefs = refs
+			switched++
+		}
+	case *apisv1.GRPCRoute:
+		for i := range r.Spec.Rules {
+			rule := &r.Spec.Rules[i]
+			refs := make([]apisv1.GRPCBackendRef, 0, len(rule.BackendRefs))
+			var filters []apisv1.GRPCRouteFilter
+			replaced := false
+			for _, ref := range rule.BackendRefs {
+				if !matchesBackend(namespace, ref.BackendObjectReference, from) {
+					refs = append(refs, ref)
+					continue
+				}
+				if !replaced {
+					filters = ref.Filters
+					replaced = true
+				}
+			}
+			if !replaced {
+				continue
+			}
+			for _, ref := range to {
+				refs = append(refs, apisv1.GRPCBackendRef{BackendRef: *ref.DeepCopy(), Filters: filters})
+			}
+			rule.BackendRefs = refs
+			switched++
+		}
+	default:
+		return 0, errors.NewMethodNotSupported(k.gvk.GroupVersion().WithResource(k.resource).GroupResource(), "switch")
+	}
+	return switched, nil
+}
+
+// matchesBackend reports whether the backendRef refers to any of backends, the
+// group, kind and namespace default to the core group, Service and namespace
+// of route.
+func matchesBackend(namespace string, ref apisv1.BackendObjectReference, backends []apisv1.BackendObjectReference) bool {
+	key := func(ref apisv1.BackendObjectReference) string {
+		group, kind, ns := "", kindService, namespace
+		if ref.Group != nil {
+			group = string(*ref.Group)
+		}
+		if ref.Kind != nil {
+			kind = string(*ref.Kind)
+		}
+		if ref.Namespace != nil {
+			ns = string(*ref.Namespace)
+		}
+		return fmt.Sprintf("%s/%s/%s/%s", group, kind, ns, ref.Name)
+	}
+	for _, backend := range backends {
+		if key(ref) != key(backend) {
+			continue
+		}
+		if backend.Port == nil || ref.Port != nil && *ref.Port == *backend.Port {
+			return true
+		}
+	}
+	return false
+}
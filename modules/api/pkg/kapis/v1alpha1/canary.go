@@ -0,0 +1,194 @@
+package v1alpha1
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kubesphere-extensions/gateway-api/pkg/api"
+	"github.com/kubesphere-extensions/gateway-api/pkg/utils/gatewayutil"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	rtclient "sigs.k8s.io/controller-runtime/pkg/client"
+	apisv1 "sigs.k8s.io/gateway-api/apis/v1"
+)
+
+// getHTTPRoute returns the HTTPRoute of request, canary releases are only
+// supported by HTTPRoutes.
+func (h *RouteHandler) getHTTPRoute(ctx context.Context, params ResourceParams) (*apisv1.HTTPRoute, error) {
+	route, ok := h.kind.newObject().(*apisv1.HTTPRoute)
+	if !ok {
+		return nil, errors.NewMethodNotSupported(h.kind.gvk.GroupVersion().WithResource(h.kind.resource).GroupResource(), "canary")
+	}
+	err := h.client.Get(ctx, types.NamespacedName{Namespace: params.Namespace, Name: params.ResourceName}, route)
+	if err != nil {
+		return nil, err
+	}
+	return route, nil
+}
+
+func (h *RouteHandler) GetCanary(c *gin.Context) {
+	params := handleRequestParams(c, resourceNameRoute)
+	route, err := h.getHTTPRoute(c.Request.Context(), params)
+	if err != nil {
+		api.HandleError(c, err)
+		return
+	}
+	canary, err := gatewayutil.CanaryOf(route)
+	if err != nil {
+		api.HandleError(c, err)
+		return
+	}
+	if canary == nil {
+		api.HandleNotFound(c, fmt.Errorf("HTTPRoute %s has no canary release", route.Name))
+		return
+	}
+	c.JSON(http.StatusOK, canary)
+}
+
+// PutCanary starts the canary release of route, or changes the weights, pinned
+// requests and steps of the active one. The rules of route when the canary
+// starts are kept as the baseline, which the rules are rendered from until the
+// canary is aborted or succeeds.
+func (h *RouteHandler) PutCanary(c *gin.Context) {
+	params := handleRequestParams(c, resourceNameRoute)
+	ctx := c.Request.Context()
+	spec := gatewayutil.CanarySpec{}
+	err := c.ShouldBindJSON(&spec)
+	if err != nil {
+		api.HandleBadRequest(c, err)
+		return
+	}
+	route, err := h.getHTTPRoute(ctx, params)
+	if err != nil {
+		api.HandleError(c, err)
+		return
+	}
+	existing, err := gatewayutil.CanaryOf(route)
+	if err != nil {
+		api.HandleError(c, err)
+		return
+	}
+
+	canary := &gatewayutil.Canary{CanarySpec: spec, Baseline: route.Spec.Rules}
+	if existing != nil && existing.Active() {
+		if !sameBackend(existing.Stable, spec.Stable) || !sameBackend(existing.Canary, spec.Canary) {
+			api.HandleConflict(c, fmt.Errorf("HTTPRoute %s has an active canary release of %s, which must be aborted first", route.Name, existing.Canary.Name))
+			return
+		}
+		canary.Baseline = existing.Baseline
+	}
+	errs := gatewayutil.ValidateCanary(canary, route.Namespace)
+	if len(errs) == 0 {
+		errs, err = h.validateCanaryBackends(ctx, route.Namespace, canary)
+		if err != nil {
+			api.HandleError(c, err)
+			return
+		}
+	}
+	if len(errs) != 0 {
+		api.HandleBadRequest(c, errs.ToAggregate())
+		return
+	}
+
+	canary.Start(time.Now())
+	err = gatewayutil.SetCanary(route, canary)
+	if err != nil {
+		api.HandleError(c, err)
+		return
+	}
+	err = h.client.Update(ctx, route)
+	if err != nil {
+		if errors.IsConflict(err) {
+			api.HandleConflict(c, err)
+			return
+		}
+		api.HandleError(c, err)
+		return
+	}
+	h.revisions.record(ctx, route, h.kind.gvk.Kind, OperationUpdate)
+	c.JSON(http.StatusOK, canary)
+}
+
+// DeleteCanary aborts the active canary release by restoring the baseline
+// rules of route, the rules of a succeeded one are kept.
+func (h *RouteHandler) DeleteCanary(c *gin.Context) {
+	params := handleRequestParams(c, resourceNameRoute)
+	ctx := c.Request.Context()
+	route, err := h.getHTTPRoute(ctx, params)
+	if err != nil {
+		api.HandleError(c, err)
+		return
+	}
+	canary, err := gatewayutil.CanaryOf(route)
+	if err != nil {
+		api.HandleError(c, err)
+		return
+	}
+	if canary == nil {
+		api.HandleNotFound(c, fmt.Errorf("HTTPRoute %s has no canary release", route.Name))
+		return
+	}
+
+	if canary.Active() {
+		route.Spec.Rules = canary.Baseline
+	}
+	delete(route.Annotations, gatewayutil.AnnotationCanary)
+	err = h.client.Update(ctx, route)
+	if err != nil {
+		if errors.IsConflict(err) {
+			api.HandleConflict(c, err)
+			return
+		}
+		api.HandleError(c, err)
+		return
+	}
+	h.revisions.record(ctx, route, h.kind.gvk.Kind, OperationUpdate)
+	c.JSON(http.StatusOK, gin.H{"status": "success"})
+}
+
+// validateCanaryBackends checks that the Services of stable and canary exist.
+func (h *RouteHandler) validateCanaryBackends(ctx context.Context, namespace string, canary *gatewayutil.Canary) (field.ErrorList, error) {
+	var errs field.ErrorList
+	for _, backend := range []struct {
+		path    *field.Path
+		backend gatewayutil.CanaryBackend
+	}{{field.NewPath("stable"), canary.Stable}, {field.NewPath("canary"), canary.Canary}} {
+		service := &corev1.Service{}
+		err := h.client.Get(ctx, types.NamespacedName{Namespace: namespace, Name: string(backend.backend.Name)}, service)
+		if err != nil {
+			if !errors.IsNotFound(err) {
+				return nil, err
+			}
+			errs = append(errs, field.NotFound(backend.path.Child("name"), backend.backend.Name))
+		}
+	}
+	return errs, nil
+}
+
+func sameBackend(a, b gatewayutil.CanaryBackend) bool {
+	if a.Name != b.Name || (a.Port == nil) != (b.Port == nil) {
+		return false
+	}
+	return a.Port == nil || *a.Port == *b.Port
+}
+
+// canaryUnchanged reports whether the update of route keeps the rules and the
+// canary release of the existing HTTPRoute.
+func canaryUnchanged(existing, route rtclient.Object) bool {
+	existingRoute, ok := existing.(*apisv1.HTTPRoute)
+	if !ok {
+		return true
+	}
+	httpRoute, ok := route.(*apisv1.HTTPRoute)
+	if !ok {
+		return false
+	}
+	return existingRoute.Annotations[gatewayutil.AnnotationCanary] == httpRoute.Annotations[gatewayutil.AnnotationCanary] &&
+		equality.Semantic.DeepEqual(existingRoute.Spec.Rules, httpRoute.Spec.Rules)
+}
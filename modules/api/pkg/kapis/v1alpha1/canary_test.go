@@ -0,0 +1,40 @@
+package v1alpha1
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/kubesphere-extensions/gateway-api/pkg/utils/gatewayutil"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	apisv1 "sigs.k8s.io/gateway-api/apis/v1"
+)
+
+func TestPutCanaryLimits(t *testing.T) {
+	backend := apisv1.HTTPBackendRef{}
+	backend.Name = "stable"
+	port := apisv1.PortNumber(80)
+	backend.Port = &port
+	rules := make([]apisv1.HTTPRouteRule, 9)
+	for i := range rules {
+		rules[i] = apisv1.HTTPRouteRule{BackendRefs: []apisv1.HTTPBackendRef{backend}}
+	}
+	route := &apisv1.HTTPRoute{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "demo", Name: "web"},
+		Spec:       apisv1.HTTPRouteSpec{Rules: rules},
+	}
+	engine, _ := newTestServer(t, route)
+
+	recorder := serve(t, engine, http.MethodPut, "/namespaces/demo/httproutes/web/canary", gatewayutil.CanarySpec{
+		Stable:  gatewayutil.CanaryBackend{Name: "stable"},
+		Canary:  gatewayutil.CanaryBackend{Name: "canary"},
+		Weight:  10,
+		Headers: []apisv1.HTTPHeaderMatch{{Name: "X-Canary", Value: "true"}},
+	})
+	if recorder.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("code = %d, want %d: %s", recorder.Code, http.StatusUnprocessableEntity, recorder.Body.String())
+	}
+	status := statusOf(t, recorder)
+	if status.Details == nil || len(status.Details.Causes) == 0 || status.Details.Causes[0].Field != "spec.rules" {
+		t.Errorf("causes = %+v, want field spec.rules", status.Details)
+	}
+}
@@ -0,0 +1,741 @@
+package v1alpha1
+
+import (
+	"context"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kubesphere-extensions/gateway-api/pkg/api"
+	"github.com/kubesphere-extensions/gateway-api/pkg/apiserver/request"
+	"github.com/kubesphere-extensions/gateway-api/pkg/utils/gatewayutil"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"k8s.io/klog/v2"
+	rtclient "sigs.k8s.io/controller-runtime/pkg/client"
+	apisv1 "sigs.k8s.io/gateway-api/apis/v1"
+	"sigs.k8s.io/gateway-api/apis/v1beta1"
+)
+
+const (
+	certManagerGroup  = "cert-manager.io"
+	kindCertificate   = "Certificate"
+	kindIssuer        = "Issuer"
+	kindClusterIssuer = "ClusterIssuer"
+	certificateReady  = "Ready"
+	secretSuffixTLS   = "tls"
+	// secretSuffixUploaded is the suffix of the Secrets of uploaded
+	// certificates, which differs from the issued ones
+	secretSuffixUploaded = "certificate"
+
+	resourceCertificates    = "certificates"
+	resourceReferenceGrants = "referencegrants"
+)
+
+var certificateGVK = schema.GroupVersionKind{Group: certManagerGroup, Version: "v1", Kind: kindCertificate}
+
+// ListenerCertificate is the certificate of a listener which terminates TLS.
+type ListenerCertificate struct {
+	Listener        apisv1.SectionName `json:"listener"`
+	Hostname        string             `json:"hostname,omitempty"`
+	SecretNamespace string             `json:"secretNamespace"`
+	SecretName      string             `json:"secretName"`
+	// Certificate is the name of cert-manager Certificate which issues the
+	// Secret, empty if the Secret is not issued for the listener
+	Certificate string `json:"certificate,omitempty"`
+
+	Ready   bool   `json:"ready"`
+	Message string `json:"message,omitempty"`
+
+	Subject     string       `json:"subject,omitempty"`
+	Issuer      string       `json:"issuer,omitempty"`
+	DNSNames    []string     `json:"dnsNames,omitempty"`
+	NotBefore   *metav1.Time `json:"notBefore,omitempty"`
+	NotAfter    *metav1.Time `json:"notAfter,omitempty"`
+	RenewalTime *metav1.Time `json:"renewalTime,omitempty"`
+}
+
+// certificateRequest is the issuer of the certificates requested by the
+// annotations of gateway.
+type certificateRequest struct {
+	issuerKind string
+	issuerName string
+	namespace  string
+}
+
+func certificateRequestOf(gateway *apisv1.Gateway) (*certificateRequest, field.ErrorList) {
+	var errs field.ErrorList
+	annotationsPath := field.NewPath("metadata", "annotations")
+	issuer, ok := gateway.Annotations[gatewayutil.AnnotationCertificateIssuer]
+	if !ok {
+		return nil, nil
+	}
+	request := &certificateRequest{issuerKind: kindClusterIssuer, issuerName: issuer, namespace: gateway.Namespace}
+	if issuer == "" {
+		errs = append(errs, field.Required(annotationsPath.Key(gatewayutil.AnnotationCertificateIssuer), ""))
+	}
+	if kind, ok := gateway.Annotations[gatewayutil.AnnotationCertificateIssuerKind]; ok {
+		if kind != kindIssuer && kind != kindClusterIssuer {
+			errs = append(errs, field.NotSupported(annotationsPath.Key(gatewayutil.AnnotationCertificateIssuerKind), kind, []string{kindIssuer, kindClusterIssuer}))
+		}
+		request.issuerKind = kind
+	}
+	if namespace, ok := gateway.Annotations[gatewayutil.AnnotationCertificateNamespace]; ok {
+		for _, msg := range validation.IsDNS1123Label(namespace) {
+			errs = append(errs, field.Invalid(annotationsPath.Key(gatewayutil.AnnotationCertificateNamespace), namespace, msg))
+		}
+		request.namespace = namespace
+	}
+	return request, errs
+}
+
+// wireCertificates sets the certificateRefs of the listeners which terminate
+// TLS without certificates to the Secrets issued by cert-manager, and returns
+// the Certificates which issue them. Nothing is changed if the gateway does
+// not request certificates.
+func wireCertificates(gateway *apisv1.Gateway) ([]*unstructured.Unstructured, error) {
+	request, errs := certificateRequestOf(gateway)
+	if request == nil || len(errs) != 0 {
+		return nil, errs.ToAggregate()
+	}
+
+	certificates := make([]*unstructured.Unstructured, 0)
+	for i := range gateway.Spec.Listeners {
+		listener := &gateway.Spec.Listeners[i]
+		if !gatewayutil.TerminatesTLS(listener) {
+			continue
+		}
+		if listener.TLS != nil && len(listener.TLS.CertificateRefs) != 0 && !issuedFor(listener.TLS.CertificateRefs[0], gateway, listener.Name, request) {
+			continue
+		}
+		if listener.Hostname == nil || *listener.Hostname == "" {
+			errs = append(errs, field.Required(field.NewPath("spec", "listeners").Index(i).Child("hostname"), "required by the certificate issued by cert-manager"))
+			continue
+		}
+
+		name := certificateName(gateway, listener.Name)
+		ref := apisv1.SecretObjectReference{Name: apisv1.ObjectName(name)}
+		if request.namespace != gateway.Namespace {
+			namespace := apisv1.Namespace(request.namespace)
+			ref.Namespace = &namespace
+		}
+		if listener.TLS == nil {
+			mode := apisv1.TLSModeTerminate
+			listener.TLS = &apisv1.GatewayTLSConfig{Mode: &mode}
+		}
+		listener.TLS.CertificateRefs = []apisv1.SecretObjectReference{ref}
+
+		certificate := &unstructured.Unstructured{}
+		certificate.SetGroupVersionKind(certificateGVK)
+		certificate.SetNamespace(request.namespace)
+		certificate.SetName(name)
+		certificate.SetLabels(map[string]string{
+			gatewayutil.LabelGatewayNamespace: gateway.Namespace,
+			gatewayutil.LabelGatewayName:      gateway.Name,
+			gatewayutil.LabelListener:         string(listener.Name),
+		})
+		certificate.Object["spec"] = map[string]interface{}{
+			"secretName": name,
+			"dnsNames":   []interface{}{string(*listener.Hostname)},
+			"issuerRef": map[string]interface{}{
+				"group": certManagerGroup,
+				"kind":  request.issuerKind,
+				"name":  request.issuerName,
+			},
+		}
+		certificates = append(certificates, certificate)
+	}
+	return certificates, errs.ToAggregate()
+}
+
+// issuedFor reports whether the certificateRef refers to the Secret issued for
+// the listener, so that it is kept in sync with the hostname of listener.
+func issuedFor(ref apisv1.SecretObjectReference, gateway *apisv1.Gateway, listener apisv1.SectionName, request *certificateRequest) bool {
+	namespace := gateway.Namespace
+	if ref.Namespace != nil {
+		namespace = string(*ref.Namespace)
+	}
+	return namespace == request.namespace && string(ref.Name) == certificateName(gateway, listener)
+}
+
+func certificateName(gateway *apisv1.Gateway, listener apisv1.SectionName) string {
+	return fmt.Sprintf("%s-%s-%s", gateway.Name, listener, secretSuffixTLS)
+}
+
+// authorizeCertificates checks that the caller may issue the certificates in
+// the namespace requested by the gateway, since the Certificates and the
+// ReferenceGrants of their Secrets are written there. The namespace of gateway
+// is authorized by the gateway itself.
+func (h *Handler) authorizeCertificates(ctx context.Context, gateway *apisv1.Gateway, certificates []*unstructured.Unstructured) error {
+	user, ok := request.UserFrom(ctx)
+	if h.authorizer == nil || !ok {
+		return nil
+	}
+	namespaces := sets.New[string]()
+	for _, certificate := range certificates {
+		if certificate.GetNamespace() != gateway.Namespace {
+			namespaces.Insert(certificate.GetNamespace())
+		}
+	}
+	for _, namespace := range sets.List(namespaces) {
+		for _, resource := range []schema.GroupResource{
+			{Group: certManagerGroup, Resource: resourceCertificates},
+			{Group: v1beta1.GroupName, Resource: resourceReferenceGrants},
+		} {
+			attrs := &request.Attributes{Verb: request.VerbCreate, Group: resource.Group, Namespace: namespace, Resource: resource.Resource}
+			allowed, reason, err := h.authorizer.Authorize(ctx, user, attrs)
+			if err != nil {
+				return err
+			}
+			if !allowed {
+				if reason == "" {
+					reason = fmt.Sprintf("user %q cannot create %s in namespace %s", user.Username, resource, namespace)
+				}
+				return errors.NewForbidden(resource, "", fmt.Errorf("%s", reason))
+			}
+		}
+	}
+	return nil
+}
+
+// checkCertificates authorizes the certificates of gateway and applies them by
+// a dry run, so that the certificates which can not be synced fail the write
+// of gateway before it is done.
+func (h *Handler) checkCertificates(ctx context.Context, gateway *apisv1.Gateway, certificates []*unstructured.Unstructured) error {
+	err := h.authorizeCertificates(ctx, gateway, certificates)
+	if err != nil {
+		return err
+	}
+	for _, certificate := range certificates {
+		err = h.checkCertificate(ctx, certificate)
+		if err != nil {
+			return err
+		}
+		err = h.client.Patch(ctx, certificate.DeepCopy(), rtclient.Apply, rtclient.FieldOwner(defaultFieldManager), rtclient.ForceOwnership, rtclient.DryRunAll)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkCertificate refuses to take over the existing Certificate which is not
+// issued for the same listener.
+func (h *Handler) checkCertificate(ctx context.Context, certificate *unstructured.Unstructured) error {
+	existing := &unstructured.Unstructured{}
+	existing.SetGroupVersionKind(certificateGVK)
+	err := h.client.Get(ctx, rtclient.ObjectKeyFromObject(certificate), existing)
+	switch {
+	case err == nil:
+		if !labels.SelectorFromSet(certificate.GetLabels()).Matches(labels.Set(existing.GetLabels())) {
+			return errors.NewAlreadyExists(schema.GroupResource{Group: certManagerGroup, Resource: resourceCertificates}, certificate.GetName())
+		}
+	case meta.IsNoMatchError(err):
+		return errors.NewBadRequest("cert-manager is not installed")
+	case !errors.IsNotFound(err):
+		return err
+	}
+	return nil
+}
+
+// syncCertificatesOrWarn syncs the certificates of gateway which is written.
+// The write is not undone by a failure of sync, which is a warning instead.
+func (h *Handler) syncCertificatesOrWarn(ctx context.Context, gateway *apisv1.Gateway, certificates []*unstructured.Unstructured) []string {
+	err := h.syncCertificates(ctx, gateway, certificates)
+	if err != nil {
+		klog.Errorf("sync certificates of gateway %s/%s: %v", gateway.Namespace, gateway.Name, err)
+		return []string{fmt.Sprintf("the certificates of gateway are not synced: %v", err)}
+	}
+	return nil
+}
+
+// syncCertificates applies the Certificates of gateway, grants the gateway to
+// refer to the issued Secrets in another namespace, and deletes the
+// Certificates which are no longer used by the gateway. The Certificates
+// which are not issued for the listeners of gateway are never taken over.
+func (h *Handler) syncCertificates(ctx context.Context, gateway *apisv1.Gateway, certificates []*unstructured.Unstructured) error {
+	keep := sets.New[types.NamespacedName]()
+	// the names of the issued Secrets in other namespaces by namespace
+	secrets := map[string][]string{}
+	for _, certificate := range certificates {
+		err := h.checkCertificate(ctx, certificate)
+		if err != nil {
+			return err
+		}
+
+		if certificate.GetNamespace() == gateway.Namespace {
+			certificate.SetOwnerReferences([]metav1.OwnerReference{*metav1.NewControllerRef(gateway, apisv1.SchemeGroupVersion.WithKind(kindGateway))})
+		} else {
+			secrets[certificate.GetNamespace()] = append(secrets[certificate.GetNamespace()], certificate.GetName())
+		}
+		err = h.client.Patch(ctx, certificate, rtclient.Apply, rtclient.FieldOwner(defaultFieldManager), rtclient.ForceOwnership)
+		if err != nil {
+			return err
+		}
+		keep.Insert(rtclient.ObjectKeyFromObject(certificate))
+	}
+
+	err := h.syncCertificateGrants(ctx, gateway, secrets)
+	if err != nil {
+		return err
+	}
+
+	existing, err := h.gatewayCertificates(ctx, gateway)
+	if err != nil {
+		return err
+	}
+	for i := range existing {
+		if keep.Has(rtclient.ObjectKeyFromObject(&existing[i])) {
+			continue
+		}
+		err = h.client.Delete(ctx, &existing[i])
+		if rtclient.IgnoreNotFound(err) != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// syncCertificateGrants keeps one ReferenceGrant of gateway in each namespace
+// of secrets, which only allows the gateway to refer to the issued Secrets.
+// The grants are labeled by the gateway, and generated names avoid the grants
+// created by others.
+func (h *Handler) syncCertificateGrants(ctx context.Context, gateway *apisv1.Gateway, secrets map[string][]string) error {
+	gatewayLabels := map[string]string{
+		gatewayutil.LabelGatewayNamespace: gateway.Namespace,
+		gatewayutil.LabelGatewayName:      gateway.Name,
+	}
+	desired := func(grant *v1beta1.ReferenceGrant, names []string) {
+		sort.Strings(names)
+		grant.Spec.From = []v1beta1.ReferenceGrantFrom{{
+			Group:     apisv1.GroupName,
+			Kind:      kindGateway,
+			Namespace: v1beta1.Namespace(gateway.Namespace),
+		}}
+		grant.Spec.To = make([]v1beta1.ReferenceGrantTo, 0, len(names))
+		for _, name := range names {
+			secretName := v1beta1.ObjectName(name)
+			grant.Spec.To = append(grant.Spec.To, v1beta1.ReferenceGrantTo{Kind: kindSecret, Name: &secretName})
+		}
+	}
+
+	list := &v1beta1.ReferenceGrantList{}
+	err := h.client.List(ctx, list, rtclient.MatchingLabels(gatewayLabels))
+	if err != nil {
+		return err
+	}
+	synced := sets.New[string]()
+	for i := range list.Items {
+		grant := &list.Items[i]
+		names, ok := secrets[grant.Namespace]
+		if !ok || synced.Has(grant.Namespace) {
+			err = h.client.Delete(ctx, grant)
+			if rtclient.IgnoreNotFound(err) != nil {
+				return err
+			}
+			continue
+		}
+		synced.Insert(grant.Namespace)
+		spec := grant.Spec.DeepCopy()
+		desired(grant, names)
+		if equality.Semantic.DeepEqual(spec, &grant.Spec) {
+			continue
+		}
+		err = h.client.Update(ctx, grant)
+		if err != nil {
+			return err
+		}
+	}
+
+	for namespace, names := range secrets {
+		if synced.Has(namespace) {
+			continue
+		}
+		grant := &v1beta1.ReferenceGrant{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace:    namespace,
+				GenerateName: fmt.Sprintf("%s-%s-", gateway.Name, secretSuffixTLS),
+				Labels:       gatewayLabels,
+			},
+		}
+		desired(grant, names)
+		err = h.client.Create(ctx, grant)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// gatewayCertificates returns the Certificates issued for the listeners of
+// gateway, nothing is returned if cert-manager is not installed.
+func (h *Handler) gatewayCertificates(ctx context.Context, gateway *apisv1.Gateway) ([]unstructured.Unstructured, error) {
+	list := &unstructured.UnstructuredList{}
+	list.SetGroupVersionKind(certificateGVK.GroupVersion().WithKind(kindCertificate + "List"))
+	err := h.client.List(ctx, list, rtclient.MatchingLabels{
+		gatewayutil.LabelGatewayNamespace: gateway.Namespace,
+		gatewayutil.LabelGatewayName:      gateway.Name,
+	})
+	if err != nil {
+		if meta.IsNoMatchError(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return list.Items, nil
+}
+
+// GetGatewayCertificates reports the readiness and expiry of the certificates
+// of the listeners which terminate TLS.
+func (h *Handler) GetGatewayCertificates(c *gin.Context) {
+	params := handleRequestParams(c, resourceNameGateway)
+	ctx := c.Request.Context()
+	gateway, err := h.getGateway(ctx, params)
+	if err != nil {
+		api.HandleError(c, err)
+		return
+	}
+
+	issued, err := h.gatewayCertificates(ctx, gateway)
+	if err != nil {
+		api.HandleError(c, err)
+		return
+	}
+	certificates := map[types.NamespacedName]*unstructured.Unstructured{}
+	for i := range issued {
+		secretName, _, _ := unstructured.NestedString(issued[i].Object, "spec", "secretName")
+		certificates[types.NamespacedName{Namespace: issued[i].GetNamespace(), Name: secretName}] = &issued[i]
+	}
+
+	items := make([]ListenerCertificate, 0)
+	for i := range gateway.Spec.Listeners {
+		listener := &gateway.Spec.Listeners[i]
+		if !gatewayutil.TerminatesTLS(listener) || listener.TLS == nil {
+			continue
+		}
+		for _, ref := range listener.TLS.CertificateRefs {
+			if (ref.Group != nil && *ref.Group != "") || (ref.Kind != nil && *ref.Kind != kindSecret) {
+				continue
+			}
+			item := ListenerCertificate{
+				Listener:        listener.Name,
+				SecretNamespace: gateway.Namespace,
+				SecretName:      string(ref.Name),
+			}
+			if listener.Hostname != nil {
+				item.Hostname = string(*listener.Hostname)
+			}
+			if ref.Namespace != nil {
+				item.SecretNamespace = string(*ref.Namespace)
+			}
+			err = h.inspectCertificate(ctx, &item, certificates[types.NamespacedName{Namespace: item.SecretNamespace, Name: item.SecretName}])
+			if err != nil {
+				api.HandleError(c, err)
+				return
+			}
+			items = append(items, item)
+		}
+	}
+	c.JSON(http.StatusOK, api.ListResult{Items: items, TotalItems: len(items)})
+}
+
+// inspectCertificate fills the certificate of Secret and the status of the
+// cert-manager Certificate which issues it.
+func (h *Handler) inspectCertificate(ctx context.Context, item *ListenerCertificate, certificate *unstructured.Unstructured) error {
+	if certificate != nil {
+		item.Certificate = certificate.GetName()
+		if renewal, ok, _ := unstructured.NestedString(certificate.Object, "status", "renewalTime"); ok {
+			if t, err := time.Parse(time.RFC3339, renewal); err == nil {
+				item.RenewalTime = &metav1.Time{Time: t}
+			}
+		}
+	}
+
+	secret := &corev1.Secret{}
+	err := h.client.Get(ctx, types.NamespacedName{Namespace: item.SecretNamespace, Name: item.SecretName}, secret)
+	if err != nil {
+		if !errors.IsNotFound(err) {
+			return err
+		}
+		item.Message = fmt.Sprintf("secret %s/%s not found", item.SecretNamespace, item.SecretName)
+		if message := certificateNotReadyMessage(certificate); message != "" {
+			item.Message = message
+		}
+		return nil
+	}
+
+	chain, err := gatewayutil.ParseCertificates(secret.Data[corev1.TLSCertKey])
+	if err != nil {
+		item.Message = fmt.Sprintf("invalid certificate: %v", err)
+		return nil
+	}
+	leaf := chain[0]
+	describeCertificate(item, leaf)
+
+	now := time.Now()
+	switch {
+	case now.Before(leaf.NotBefore):
+		item.Message = "the certificate is not valid yet"
+	case now.After(leaf.NotAfter):
+		item.Message = "the certificate has expired"
+	case item.Hostname != "" && leaf.VerifyHostname(item.Hostname) != nil:
+		item.Message = fmt.Sprintf("the certificate does not cover hostname %s", item.Hostname)
+	default:
+		item.Message = certificateNotReadyMessage(certificate)
+		item.Ready = item.Message == ""
+	}
+	return nil
+}
+
+func describeCertificate(item *ListenerCertificate, leaf *x509.Certificate) {
+	item.Subject = leaf.Subject.String()
+	item.Issuer = leaf.Issuer.String()
+	item.DNSNames = leaf.DNSNames
+	item.NotBefore = &metav1.Time{Time: leaf.NotBefore}
+	item.NotAfter = &metav1.Time{Time: leaf.NotAfter}
+}
+
+// certificateNotReadyMessage returns the message of the Ready condition of
+// cert-manager Certificate which is not true.
+func certificateNotReadyMessage(certificate *unstructured.Unstructured) string {
+	if certificate == nil {
+		return ""
+	}
+	conditions, _, _ := unstructured.NestedSlice(certificate.Object, "status", "conditions")
+	for _, c := range conditions {
+		condition, ok := c.(map[string]interface{})
+		if !ok || condition["type"] != certificateReady {
+			continue
+		}
+		if condition["status"] == string(metav1.ConditionTrue) {
+			return ""
+		}
+		return fmt.Sprintf("certificate %s is not ready: %v", certificate.GetName(), condition["message"])
+	}
+	return fmt.Sprintf("certificate %s is not ready", certificate.GetName())
+}
+
+// CertificateUpload is a certificate uploaded for a listener, which is either
+// the PEM encoded certificate chain and private key, or a PKCS #12 bundle.
+type CertificateUpload struct {
+	Listener apisv1.SectionName `json:"listener"`
+	// SecretName is the Secret in the namespace of gateway to store the
+	// certificate, defaults to <gateway>-<listener>-certificate
+	SecretName string `json:"secretName,omitempty"`
+
+	Certificate string `json:"certificate,omitempty"`
+	PrivateKey  string `json:"privateKey,omitempty"`
+	// PKCS12 is the base64 encoded PKCS #12 bundle, which is decrypted by Password
+	PKCS12   []byte `json:"pkcs12,omitempty"`
+	Password string `json:"password,omitempty"`
+}
+
+// UploadGatewayCertificate stores the uploaded certificate as a kubernetes.io/tls
+// Secret next to the gateway, and sets it as the certificate of the listener.
+// The Secret is written as the apiserver, since the callers may not write the
+// Secrets in the namespace of gateway. Only the Secrets of the certificates
+// uploaded for the gateway are replaced.
+func (h *Handler) UploadGatewayCertificate(c *gin.Context) {
+	params := handleRequestParams(c, resourceNameGateway)
+	ctx := c.Request.Context()
+	dryRun, err := isDryRun(c)
+	if err != nil {
+		api.HandleBadRequest(c, err)
+		return
+	}
+	upload := &CertificateUpload{}
+	err = c.ShouldBindJSON(upload)
+	if err != nil {
+		api.HandleBadRequest(c, err)
+		return
+	}
+	gateway, err := h.getGateway(ctx, params)
+	if err != nil {
+		api.HandleError(c, err)
+		return
+	}
+
+	var errs field.ErrorList
+	var listener *apisv1.Listener
+	for i := range gateway.Spec.Listeners {
+		if gateway.Spec.Listeners[i].Name == upload.Listener {
+			listener = &gateway.Spec.Listeners[i]
+		}
+	}
+	switch {
+	case listener == nil:
+		errs = append(errs, field.NotFound(field.NewPath("listener"), upload.Listener))
+	case !gatewayutil.TerminatesTLS(listener):
+		errs = append(errs, field.Invalid(field.NewPath("listener"), upload.Listener, "the listener does not terminate TLS"))
+	}
+	if upload.SecretName == "" {
+		upload.SecretName = fmt.Sprintf("%s-%s-%s", gateway.Name, upload.Listener, secretSuffixUploaded)
+	}
+	for _, msg := range validation.IsDNS1123Subdomain(upload.SecretName) {
+		errs = append(errs, field.Invalid(field.NewPath("secretName"), upload.SecretName, msg))
+	}
+	certPEM, keyPEM, errs := certificateOf(upload, errs)
+	if len(errs) != 0 {
+		api.HandleBadRequest(c, errs.ToAggregate())
+		return
+	}
+
+	chain, err := gatewayutil.ParseKeyPair(certPEM, keyPEM)
+	if err != nil {
+		api.HandleBadRequest(c, field.Invalid(field.NewPath("certificate"), "", err.Error()))
+		return
+	}
+	leaf := chain[0]
+	if listener.Hostname != nil && *listener.Hostname != "" {
+		err = leaf.VerifyHostname(string(*listener.Hostname))
+		if err != nil {
+			api.HandleBadRequest(c, field.Invalid(field.NewPath("certificate"), "", fmt.Sprintf("the certificate does not cover hostname %s of listener", *listener.Hostname)))
+			return
+		}
+	}
+	if time.Now().After(leaf.NotAfter) {
+		api.HandleBadRequest(c, field.Invalid(field.NewPath("certificate"), "", "the certificate has expired"))
+		return
+	}
+
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: gateway.Namespace,
+			Name:      upload.SecretName,
+			Labels: map[string]string{
+				gatewayutil.LabelGatewayNamespace: gateway.Namespace,
+				gatewayutil.LabelGatewayName:      gateway.Name,
+				gatewayutil.LabelListener:         string(listener.Name),
+			},
+			OwnerReferences: []metav1.OwnerReference{*metav1.NewControllerRef(gateway, apisv1.SchemeGroupVersion.WithKind(kindGateway))},
+		},
+		Type: corev1.SecretTypeTLS,
+		Data: map[string][]byte{
+			corev1.TLSCertKey:       certPEM,
+			corev1.TLSPrivateKeyKey: keyPEM,
+		},
+	}
+	if listener.TLS == nil {
+		mode := apisv1.TLSModeTerminate
+		listener.TLS = &apisv1.GatewayTLSConfig{Mode: &mode}
+	}
+	listener.TLS.CertificateRefs = []apisv1.SecretObjectReference{{Name: apisv1.ObjectName(secret.Name)}}
+	// the gateway is checked by a dry run first, so that an invalid gateway
+	// does not leave the Secret behind. The warnings are written by the update.
+	if !dryRun {
+		_, err = h.writeGateway(ctx, gateway.DeepCopy(), true)
+		if err != nil {
+			if errors.IsConflict(err) {
+				api.HandleConflict(c, err)
+				return
+			}
+			api.HandleError(c, err)
+			return
+		}
+	}
+	previous, err := h.writeCertificateSecret(ctx, gateway, secret, dryRun)
+	if err != nil {
+		api.HandleError(c, err)
+		return
+	}
+	if !h.updateGateway(c, gateway, dryRun) {
+		if !dryRun {
+			h.restoreCertificateSecret(ctx, secret, previous)
+		}
+		return
+	}
+
+	item := ListenerCertificate{
+		Listener:        listener.Name,
+		SecretNamespace: secret.Namespace,
+		SecretName:      secret.Name,
+		Ready:           true,
+	}
+	if listener.Hostname != nil {
+		item.Hostname = string(*listener.Hostname)
+	}
+	describeCertificate(&item, leaf)
+	c.JSON(http.StatusOK, item)
+}
+
+// certificateOf returns the PEM encoded certificate chain and private key of
+// the upload.
+func certificateOf(upload *CertificateUpload, errs field.ErrorList) ([]byte, []byte, field.ErrorList) {
+	switch {
+	case len(upload.PKCS12) != 0:
+		if upload.Certificate != "" || upload.PrivateKey != "" {
+			return nil, nil, append(errs, field.Forbidden(field.NewPath("pkcs12"), "may not be specified with certificate and privateKey"))
+		}
+		certPEM, keyPEM, err := gatewayutil.ConvertPKCS12(upload.PKCS12, upload.Password)
+		if err != nil {
+			return nil, nil, append(errs, field.Invalid(field.NewPath("pkcs12"), "", err.Error()))
+		}
+		return certPEM, keyPEM, errs
+	case upload.Certificate == "" && upload.PrivateKey == "":
+		return nil, nil, append(errs, field.Required(field.NewPath("certificate"), "either certificate and privateKey or pkcs12 is required"))
+	case upload.Certificate == "":
+		return nil, nil, append(errs, field.Required(field.NewPath("certificate"), ""))
+	case upload.PrivateKey == "":
+		return nil, nil, append(errs, field.Required(field.NewPath("privateKey"), ""))
+	default:
+		return []byte(upload.Certificate), []byte(upload.PrivateKey), errs
+	}
+}
+
+// writeCertificateSecret creates the Secret, or replaces the Secret of the
+// certificate uploaded for the same gateway, and returns the replaced Secret,
+// which is nil if the Secret is created. The other Secrets are never touched,
+// since the Secret is written as the apiserver.
+func (h *Handler) writeCertificateSecret(ctx context.Context, gateway *apisv1.Gateway, secret *corev1.Secret, dryRun bool) (*corev1.Secret, error) {
+	existing := &corev1.Secret{}
+	err := h.serviceClient.Get(ctx, rtclient.ObjectKeyFromObject(secret), existing)
+	if err != nil {
+		if !errors.IsNotFound(err) {
+			return nil, err
+		}
+		var opts []rtclient.CreateOption
+		if dryRun {
+			opts = append(opts, rtclient.DryRunAll)
+		}
+		return nil, h.serviceClient.Create(ctx, secret, opts...)
+	}
+
+	if existing.Type != corev1.SecretTypeTLS || !ownedBy(existing, gateway) {
+		return nil, errors.NewAlreadyExists(corev1.Resource("secrets"), secret.Name)
+	}
+	secret.ResourceVersion = existing.ResourceVersion
+	var opts []rtclient.UpdateOption
+	if dryRun {
+		opts = append(opts, rtclient.DryRunAll)
+	}
+	return existing, h.serviceClient.Update(ctx, secret, opts...)
+}
+
+// restoreCertificateSecret undoes writeCertificateSecret after the gateway
+// fails to refer to the Secret. The created Secret is deleted, and the
+// replaced one gets its certificate back.
+func (h *Handler) restoreCertificateSecret(ctx context.Context, secret, previous *corev1.Secret) {
+	var err error
+	if previous == nil {
+		err = rtclient.IgnoreNotFound(h.serviceClient.Delete(ctx, secret))
+	} else {
+		previous.ResourceVersion = secret.ResourceVersion
+		err = h.serviceClient.Update(ctx, previous)
+	}
+	if err != nil {
+		klog.Errorf("failed to restore Secret %s/%s: %v", secret.Namespace, secret.Name, err)
+	}
+}
@@ -0,0 +1,247 @@
+package v1alpha1
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kubesphere-extensions/gateway-api/pkg/utils/gatewayutil"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	rtclient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+	apisv1 "sigs.k8s.io/gateway-api/apis/v1"
+)
+
+func TestUploadGatewayCertificate(t *testing.T) {
+	certPEM, keyPEM := newKeyPair(t)
+	secretKey := types.NamespacedName{Namespace: "demo", Name: "gw-https-certificate"}
+
+	tests := []struct {
+		name string
+		// existing is the data of the Secret uploaded before
+		existing []byte
+		// failUpdate fails the update of gateway
+		failUpdate bool
+		wantCode   int
+		// wantCert is the certificate of the Secret after the upload, nil if
+		// there is no Secret
+		wantCert []byte
+	}{
+		{
+			name:     "new Secret",
+			wantCode: http.StatusOK,
+			wantCert: certPEM,
+		},
+		{
+			name:     "replaced Secret",
+			existing: []byte("old"),
+			wantCode: http.StatusOK,
+			wantCert: certPEM,
+		},
+		{
+			name:       "created Secret is deleted",
+			failUpdate: true,
+			wantCode:   http.StatusConflict,
+		},
+		{
+			name:       "replaced Secret is restored",
+			existing:   []byte("old"),
+			failUpdate: true,
+			wantCode:   http.StatusConflict,
+			wantCert:   []byte("old"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gateway := testGateway("demo", "gw", map[string]string{
+				gatewayutil.LabelScope:            gatewayutil.ScopeNamespace,
+				gatewayutil.LabelWorkingNamespace: "demo",
+			})
+			gateway.UID = "gateway-uid"
+			mode := apisv1.TLSModeTerminate
+			gateway.Spec.Listeners = append(gateway.Spec.Listeners, apisv1.Listener{
+				Name: "https", Protocol: apisv1.HTTPSProtocolType, Port: 443,
+				TLS: &apisv1.GatewayTLSConfig{Mode: &mode},
+			})
+			objects := []rtclient.Object{testGatewayClass(), gateway}
+			if tt.existing != nil {
+				objects = append(objects, &corev1.Secret{
+					ObjectMeta: metav1.ObjectMeta{
+						Namespace:       secretKey.Namespace,
+						Name:            secretKey.Name,
+						OwnerReferences: []metav1.OwnerReference{*metav1.NewControllerRef(gateway, apisv1.SchemeGroupVersion.WithKind(kindGateway))},
+					},
+					Type: corev1.SecretTypeTLS,
+					Data: map[string][]byte{corev1.TLSCertKey: tt.existing, corev1.TLSPrivateKeyKey: tt.existing},
+				})
+			}
+			engine, client := newInterceptedTestServer(t, interceptor.Funcs{
+				Update: func(ctx context.Context, client rtclient.WithWatch, obj rtclient.Object, opts ...rtclient.UpdateOption) error {
+					updateOptions := &rtclient.UpdateOptions{}
+					updateOptions.ApplyOptions(opts)
+					if _, ok := obj.(*apisv1.Gateway); ok && tt.failUpdate && len(updateOptions.DryRun) == 0 {
+						return errors.NewConflict(apisv1.Resource("gateways"), obj.GetName(), fmt.Errorf("changed"))
+					}
+					return client.Update(ctx, obj, opts...)
+				},
+			}, objects...)
+
+			recorder := serve(t, engine, http.MethodPost, "/namespaces/demo/gateways/gw/certificates", &CertificateUpload{
+				Listener:    "https",
+				Certificate: string(certPEM),
+				PrivateKey:  string(keyPEM),
+			})
+			if recorder.Code != tt.wantCode {
+				t.Fatalf("code = %d, want %d: %s", recorder.Code, tt.wantCode, recorder.Body.String())
+			}
+			warnings := recorder.Header().Values("Warning")
+			seen := map[string]bool{}
+			for _, warning := range warnings {
+				if seen[warning] {
+					t.Errorf("warning %s is repeated", warning)
+				}
+				seen[warning] = true
+			}
+
+			secret := &corev1.Secret{}
+			err := client.Get(context.Background(), secretKey, secret)
+			if tt.wantCert == nil {
+				if !errors.IsNotFound(err) {
+					t.Errorf("Secret is left behind: %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(secret.Data[corev1.TLSCertKey], tt.wantCert) {
+				t.Errorf("certificate = %q, want %q", secret.Data[corev1.TLSCertKey], tt.wantCert)
+			}
+		})
+	}
+}
+
+// newKeyPair returns a PEM encoded self signed certificate and its key.
+func newKeyPair(t *testing.T) ([]byte, []byte) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "example.com"},
+		DNSNames:     []string{"example.com"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+}
+
+func TestCreateGatewayCertificates(t *testing.T) {
+	invalid := errors.NewInvalid(certificateGVK.GroupKind(), "gw-https-tls", nil)
+	tests := []struct {
+		name      string
+		dryRunErr error
+		syncErr   error
+		wantCode  int
+		// wantWarning is whether the failure of sync is warned
+		wantWarning bool
+		wantCreated bool
+	}{
+		{
+			name:        "certificates are synced",
+			wantCode:    http.StatusOK,
+			wantCreated: true,
+		},
+		{
+			name:      "certificate is refused before the gateway is written",
+			dryRunErr: invalid,
+			wantCode:  http.StatusUnprocessableEntity,
+		},
+		{
+			name:        "failure of sync is a warning",
+			syncErr:     fmt.Errorf("connection refused"),
+			wantCode:    http.StatusOK,
+			wantWarning: true,
+			wantCreated: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var synced bool
+			engine, client := newInterceptedTestServer(t, interceptor.Funcs{
+				Get: func(ctx context.Context, client rtclient.WithWatch, key rtclient.ObjectKey, obj rtclient.Object, opts ...rtclient.GetOption) error {
+					if obj.GetObjectKind().GroupVersionKind() == certificateGVK {
+						return errors.NewNotFound(schema.GroupResource{Group: certManagerGroup, Resource: resourceCertificates}, key.Name)
+					}
+					return client.Get(ctx, key, obj, opts...)
+				},
+				Patch: func(ctx context.Context, client rtclient.WithWatch, obj rtclient.Object, patch rtclient.Patch, opts ...rtclient.PatchOption) error {
+					patchOptions := &rtclient.PatchOptions{}
+					patchOptions.ApplyOptions(opts)
+					if len(patchOptions.DryRun) != 0 {
+						return tt.dryRunErr
+					}
+					synced = true
+					return tt.syncErr
+				},
+				List: func(ctx context.Context, client rtclient.WithWatch, list rtclient.ObjectList, opts ...rtclient.ListOption) error {
+					if _, ok := list.(*unstructured.UnstructuredList); ok {
+						return nil
+					}
+					return client.List(ctx, list, opts...)
+				},
+			}, testGatewayClass())
+
+			gateway := testGateway("", "gw", nil)
+			gateway.Annotations = map[string]string{gatewayutil.AnnotationCertificateIssuer: "letsencrypt"}
+			hostname := apisv1.Hostname("example.com")
+			gateway.Spec.Listeners = append(gateway.Spec.Listeners, apisv1.Listener{
+				Name: "https", Protocol: apisv1.HTTPSProtocolType, Port: 443, Hostname: &hostname,
+			})
+			recorder := serve(t, engine, http.MethodPost, "/namespaces/demo/gateways", gateway)
+			if recorder.Code != tt.wantCode {
+				t.Fatalf("code = %d, want %d: %s", recorder.Code, tt.wantCode, recorder.Body.String())
+			}
+			if synced != tt.wantCreated {
+				t.Errorf("synced = %v, want %v", synced, tt.wantCreated)
+			}
+			warned := false
+			for _, warning := range recorder.Header().Values("Warning") {
+				warned = warned || strings.Contains(warning, "not synced")
+			}
+			if warned != tt.wantWarning {
+				t.Errorf("warnings = %v, want warning %v", recorder.Header().Values("Warning"), tt.wantWarning)
+			}
+			err := client.Get(context.Background(), types.NamespacedName{Namespace: defaultWorkingNamespace, Name: "gw"}, &apisv1.Gateway{})
+			if created := err == nil; created != tt.wantCreated {
+				t.Errorf("created = %v, want %v: %v", created, tt.wantCreated, err)
+			}
+		})
+	}
+}
@@ -2,47 +2,80 @@ package v1alpha1
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"io"
 	"k8s.io/apimachinery/pkg/types"
 	"net/http"
+	"sort"
 	"strconv"
 	"strings"
 
+	jsonpatch "github.com/evanphx/json-patch/v5"
 	"github.com/gin-gonic/gin"
 	"github.com/kubesphere-extensions/gateway-api/pkg/api"
+	"github.com/kubesphere-extensions/gateway-api/pkg/apiserver/authorization"
+	"github.com/kubesphere-extensions/gateway-api/pkg/apiserver/query"
+	"github.com/kubesphere-extensions/gateway-api/pkg/apiserver/request"
+	"github.com/kubesphere-extensions/gateway-api/pkg/apiserver/watch"
+	"github.com/kubesphere-extensions/gateway-api/pkg/utils/gatewayutil"
+	"k8s.io/apimachinery/pkg/api/equality"
 	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/selection"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
 	rtclient "sigs.k8s.io/controller-runtime/pkg/client"
 	apisv1 "sigs.k8s.io/gateway-api/apis/v1"
+	"sigs.k8s.io/yaml"
 )
 
 const (
-	scopeNamespace = "namespace"
-	scopeWorkspace = "workspace"
-	scopeCluster   = "cluster"
-	paramWorkspace = scopeWorkspace
-	paramNamespace = scopeNamespace
+	paramWorkspace = "workspace"
+	paramNamespace = "namespace"
+	paramWatch     = "watch"
 
 	resourceNameGateway = "gateway"
+	resourceGateways    = "gateways"
+	kindGateway         = "Gateway"
+
+	defaultFieldManager = "gateway-apiserver"
+
+	filterGatewayClassName = "gatewayClassName"
 
 	kubesphereControlsSystem = "kubesphere-controls-system"
 	defaultWorkingNamespace  = kubesphereControlsSystem
-
-	workingNamespace        = "gatewayapi.kubesphere.io/working-namespace"
-	workingWorkspace        = "gatewayapi.kubesphere.io/working-workspace"
-	gatewayApiScope         = "gatewayapi.kubesphere.io/scope"
-	gatewayListener         = "gatewayapi.kubesphere.io/listener"
-	gatewayListenerProtocol = "gatewayapi.kubesphere.io/listener.%s.protocols"
-	gatewayListenerPort     = "gatewayapi.kubesphere.io/listener.%s.port"
 )
 
 type Handler struct {
 	client rtclient.Client
+	// serviceClient does the requests as the apiserver, it writes the Secrets
+	// of the certificates uploaded by the callers who may update gateways
+	serviceClient rtclient.Client
+	revisions     *revisionHistory
+	// authorizer filters the gateways listed in cluster scope, nil if authorization is disabled
+	authorizer authorization.Authorizer
+	// gateways serves the watches of gateways, nil if watch is not supported
+	gateways *watch.Broadcaster
+}
+
+type GatewayClassSummary struct {
+	ControllerName    string                      `json:"controllerName"`
+	ParametersRef     *apisv1.ParametersReference `json:"parametersRef,omitempty"`
+	Accepted          *metav1.Condition           `json:"accepted,omitempty"`
+	SupportedFeatures []apisv1.SupportedFeature   `json:"supportedFeatures,omitempty"`
+	Listeners         []gatewayutil.Listener      `json:"listeners,omitempty"`
+	Error             string                      `json:"error,omitempty"`
 }
 
-type Listener struct {
-	Name      string   `json:"name"`
-	Protocols []string `json:"protocols,omitempty"`
-	Port      int32    `json:"port,omitempty"`
+type GatewayClass struct {
+	apisv1.GatewayClass `json:",inline"`
+	Summary             GatewayClassSummary `json:"summary"`
 }
 
 type ResourceParams struct {
@@ -52,22 +85,17 @@ type ResourceParams struct {
 	ResourceName string
 }
 
-func NewHandler(client rtclient.Client) *Handler {
-	return &Handler{client: client}
+func NewHandler(client, serviceClient rtclient.Client, authorizer authorization.Authorizer, informers cache.Informers) *Handler {
+	h := &Handler{client: client, serviceClient: serviceClient, revisions: &revisionHistory{client: serviceClient}, authorizer: authorizer}
+	if informers != nil {
+		h.gateways = watch.NewBroadcaster(informers, func() rtclient.Object { return &apisv1.Gateway{} })
+	}
+	return h
 }
 
 func (h *Handler) getGateway(ctx context.Context, params ResourceParams) (*apisv1.Gateway, error) {
 	list := &apisv1.GatewayList{}
-	labelMap := map[string]string{}
-	labelMap[gatewayApiScope] = params.Scope
-	if params.Workspace != "" {
-		labelMap[workingWorkspace] = params.Workspace
-	}
-	if params.Namespace != "" {
-		labelMap[workingNamespace] = params.Namespace
-	}
-
-	err := h.client.List(ctx, list, rtclient.MatchingLabels(labelMap), rtclient.InNamespace(""))
+	err := h.client.List(ctx, list, rtclient.MatchingLabels(scopeLabels(params)), rtclient.InNamespace(""))
 	if err != nil {
 		return nil, err
 	}
@@ -86,6 +114,116 @@ func (h *Handler) getGateway(ctx context.Context, params ResourceParams) (*apisv
 	return gateway, nil
 }
 
+// listGateways lists the gateways of the scope which match the label selector
+// and filters of query, the result is sorted but not paginated.
+func (h *Handler) listGateways(ctx context.Context, params ResourceParams, q *query.Query) ([]apisv1.Gateway, error) {
+	selector, err := gatewaySelector(params, q)
+	if err != nil {
+		return nil, err
+	}
+
+	list := &apisv1.GatewayList{}
+	err = h.client.List(ctx, list, rtclient.MatchingLabelsSelector{Selector: selector}, rtclient.InNamespace(""))
+	if err != nil {
+		return nil, err
+	}
+
+	visible, err := h.gatewayVisibility(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+
+	gateways := make([]apisv1.Gateway, 0, len(list.Items))
+	for _, item := range list.Items {
+		if ok, err := visible(&item); err != nil {
+			return nil, err
+		} else if !ok {
+			continue
+		}
+		if !matchesGatewayFilters(&item, q) {
+			continue
+		}
+		gateways = append(gateways, item)
+	}
+
+	sort.SliceStable(gateways, func(i, j int) bool {
+		return q.Compare(&gateways[i], &gateways[j])
+	})
+	return gateways, nil
+}
+
+// gatewaySelector returns the label selector of query which also selects the
+// gateways of the scope.
+func gatewaySelector(params ResourceParams, q *query.Query) (labels.Selector, error) {
+	selector, err := labels.Parse(q.LabelSelector)
+	if err != nil {
+		return nil, errors.NewBadRequest(err.Error())
+	}
+	for key, value := range scopeLabels(params) {
+		requirement, err := labels.NewRequirement(key, selection.Equals, []string{value})
+		if err != nil {
+			return nil, errors.NewBadRequest(err.Error())
+		}
+		selector = selector.Add(*requirement)
+	}
+	return selector, nil
+}
+
+// matchesGatewayFilters reports whether the gateway matches the name and
+// gatewayClassName filters of query.
+func matchesGatewayFilters(gateway *apisv1.Gateway, q *query.Query) bool {
+	if name := q.Filters[query.ParameterName]; name != "" && !strings.Contains(gateway.Name, name) {
+		return false
+	}
+	if className := q.Filters[filterGatewayClassName]; className != "" && string(gateway.Spec.GatewayClassName) != className {
+		return false
+	}
+	return true
+}
+
+// gatewayVisibility returns a function which reports whether the caller may
+// list a gateway. The caller who cannot list gateways in cluster scope only
+// sees the gateways of the namespaces which it may list, the workspace scoped
+// gateways are authorized in cluster scope as well.
+func (h *Handler) gatewayVisibility(ctx context.Context, params ResourceParams) (func(*apisv1.Gateway) (bool, error), error) {
+	all := func(*apisv1.Gateway) (bool, error) { return true, nil }
+	user, ok := request.UserFrom(ctx)
+	if h.authorizer == nil || !ok || params.Scope != gatewayutil.ScopeCluster {
+		return all, nil
+	}
+
+	authorize := func(attrs *request.Attributes) (bool, error) {
+		allowed, _, err := h.authorizer.Authorize(ctx, user, attrs)
+		return allowed, err
+	}
+	allowed, err := authorize(&request.Attributes{Verb: request.VerbList, Resource: resourceGateways})
+	if err != nil || allowed {
+		return all, err
+	}
+
+	decisions := map[string]bool{}
+	return func(gateway *apisv1.Gateway) (bool, error) {
+		if gateway.Labels[gatewayutil.LabelScope] != gatewayutil.ScopeNamespace {
+			return false, nil
+		}
+		attrs := &request.Attributes{Verb: request.VerbList, Resource: resourceGateways, Namespace: gateway.Labels[gatewayutil.LabelWorkingNamespace]}
+		if attrs.Namespace == "" {
+			return false, nil
+		}
+
+		key := attrs.Namespace
+		if decision, ok := decisions[key]; ok {
+			return decision, nil
+		}
+		decision, err := authorize(attrs)
+		if err != nil {
+			return false, err
+		}
+		decisions[key] = decision
+		return decision, nil
+	}, nil
+}
+
 func (h *Handler) GetGateway(c *gin.Context) {
 	gwParams := handleRequestParams(c, resourceNameGateway)
 	gateway, err := h.getGateway(c.Request.Context(), gwParams)
@@ -99,21 +237,36 @@ func (h *Handler) GetGateway(c *gin.Context) {
 
 func (h *Handler) ListGateways(c *gin.Context) {
 	gwParams := handleRequestParams(c, resourceNameGateway)
-	list := &apisv1.GatewayList{}
-	labelMap := map[string]string{}
-	labelMap[gatewayApiScope] = gwParams.Scope
-	if gwParams.Workspace != "" {
-		labelMap[workingWorkspace] = gwParams.Workspace
-	}
-	if gwParams.Namespace != "" {
-		labelMap[workingNamespace] = gwParams.Namespace
+	q := query.ParseQueryParameter(c)
+	if watching, _ := strconv.ParseBool(c.Query(paramWatch)); watching {
+		h.watchGateways(c, gwParams, q)
+		return
 	}
-
-	err := h.client.List(c.Request.Context(), list, rtclient.MatchingLabels(labelMap), rtclient.InNamespace(""))
+	gateways, err := h.listGateways(c.Request.Context(), gwParams, q)
 	if err != nil {
 		api.HandleError(c, err)
 		return
 	}
+
+	start, end := q.Pagination.GetValidPagination(len(gateways))
+	if withStatus, _ := strconv.ParseBool(c.Query(paramWithStatus)); withStatus {
+		page := make([]*apisv1.Gateway, 0, end-start)
+		for i := start; i < end; i++ {
+			page = append(page, &gateways[i])
+		}
+		workloads, err := h.newWorkloadIndex(c.Request.Context(), page...)
+		if err != nil {
+			api.HandleError(c, err)
+			return
+		}
+		items := make([]Gateway, 0, len(page))
+		for _, gateway := range page {
+			items = append(items, Gateway{Gateway: *gateway, Summary: gatewayStatusSummary(gateway, workloads)})
+		}
+		c.JSON(http.StatusOK, api.ListResult{Items: items, TotalItems: len(gateways)})
+		return
+	}
+	c.JSON(http.StatusOK, api.ListResult{Items: gateways[start:end], TotalItems: len(gateways)})
 }
 
 func (h *Handler) CreateGateway(c *gin.Context) {
@@ -124,48 +277,531 @@ func (h *Handler) CreateGateway(c *gin.Context) {
 		api.HandleBadRequest(c, err)
 		return
 	}
+
+	h.createGateway(c, params, gateway)
+}
+
+// createGateway defaults the scope labels, namespace and AllowedRoutes of the
+// gateway, validates and creates it, and writes the created gateway.
+func (h *Handler) createGateway(c *gin.Context, params ResourceParams, gateway *apisv1.Gateway) {
+	dryRun, err := isDryRun(c)
+	if err != nil {
+		api.HandleBadRequest(c, err)
+		return
+	}
+	errs := setScope(params, gateway)
+	if len(errs) != 0 {
+		api.HandleBadRequest(c, errs.ToAggregate())
+		return
+	}
+
+	err = gatewayutil.DefaultAllowedRoutes(gateway)
+	if err != nil {
+		api.HandleBadRequest(c, err)
+		return
+	}
+	certificates, err := wireCertificates(gateway)
+	if err == nil {
+		err = h.checkCertificates(c.Request.Context(), gateway, certificates)
+	}
+	if err != nil {
+		api.HandleError(c, err)
+		return
+	}
+	err = h.validateGateway(c, gateway)
+	if err != nil {
+		api.HandleError(c, err)
+		return
+	}
+
+	var opts []rtclient.CreateOption
+	if dryRun {
+		opts = append(opts, rtclient.DryRunAll)
+	}
+	err = h.client.Create(c.Request.Context(), gateway, opts...)
+	if err != nil {
+		api.HandleError(c, err)
+		return
+	}
+	if !dryRun {
+		addWarnings(c, h.syncCertificatesOrWarn(c.Request.Context(), gateway, certificates))
+		h.revisions.record(c.Request.Context(), gateway, kindGateway, OperationCreate)
+	}
+	c.JSON(http.StatusOK, gateway)
+}
+
+// setScope sets the scope labels of gateway to the request scope, and the
+// namespace of gateway to the default working namespace if it is empty. The
+// labels and namespace which disagree with the request scope are refused,
+// since the request is authorized in its scope only.
+func setScope(params ResourceParams, gateway *apisv1.Gateway) field.ErrorList {
+	var errs field.ErrorList
+	labelsPath := field.NewPath("metadata", "labels")
+	expected := scopeLabels(params)
 	if gateway.Labels == nil {
 		gateway.Labels = map[string]string{}
 	}
-	if _, ok := gateway.Labels[workingNamespace]; !ok && params.Namespace != "" {
-		gateway.Labels[workingNamespace] = params.Namespace
+	for _, key := range gatewayutil.ScopeLabelKeys {
+		value, ok := gateway.Labels[key]
+		want, wanted := expected[key]
+		switch {
+		case ok && !wanted:
+			errs = append(errs, field.Forbidden(labelsPath.Key(key), fmt.Sprintf("not allowed in %s scope", params.Scope)))
+		case ok && value != want:
+			errs = append(errs, field.Invalid(labelsPath.Key(key), value, fmt.Sprintf("must be %s", want)))
+		case wanted:
+			gateway.Labels[key] = want
+		}
+	}
+
+	if gateway.Namespace == "" {
+		gateway.Namespace = defaultWorkingNamespace
+	}
+	// a namespace scoped gateway may also live in its working namespace
+	if gateway.Namespace != defaultWorkingNamespace && gateway.Namespace != params.Namespace {
+		allowed := defaultWorkingNamespace
+		if params.Namespace != "" {
+			allowed = fmt.Sprintf("%s or %s", defaultWorkingNamespace, params.Namespace)
+		}
+		errs = append(errs, field.Invalid(field.NewPath("metadata", "namespace"), gateway.Namespace, fmt.Sprintf("must be %s", allowed)))
+	}
+	return errs
+}
+
+// validateGateway validates the gateway to be written as an update of the
+// existing one if there is, the warnings are written to the Warning headers of
+// response.
+func (h *Handler) validateGateway(c *gin.Context, gateway *apisv1.Gateway) error {
+	warnings, err := h.checkGateway(c.Request.Context(), gateway)
+	addWarnings(c, warnings)
+	return err
+}
+
+// checkGateway validates the gateway to be written as an update of the
+// existing one if there is, and returns the warnings of validation.
+func (h *Handler) checkGateway(ctx context.Context, gateway *apisv1.Gateway) ([]string, error) {
+	old := &apisv1.Gateway{}
+	err := h.client.Get(ctx, rtclient.ObjectKeyFromObject(gateway), old)
+	if errors.IsNotFound(err) {
+		old, err = nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	result, err := gatewayutil.ValidateGateway(ctx, h.client, gateway, old)
+	if err != nil {
+		return nil, err
+	}
+	if len(result.Errors) != 0 {
+		return result.Warnings, errors.NewInvalid(apisv1.SchemeGroupVersion.WithKind(kindGateway).GroupKind(), gateway.Name, result.Errors)
+	}
+	return result.Warnings, nil
+}
+
+// addWarnings writes the warnings to the Warning headers of response.
+func addWarnings(c *gin.Context, warnings []string) {
+	for _, warning := range warnings {
+		c.Writer.Header().Add("Warning", fmt.Sprintf("299 - %q", warning))
+	}
+}
+
+// isDryRun reports whether the request asks for a dry run by dryRun=All.
+func isDryRun(c *gin.Context) (bool, error) {
+	switch value := c.Query("dryRun"); value {
+	case "":
+		return false, nil
+	case metav1.DryRunAll:
+		return true, nil
+	default:
+		return false, field.NotSupported(field.NewPath("dryRun"), value, []string{metav1.DryRunAll})
+	}
+}
+
+func (h *Handler) UpdateGateway(c *gin.Context) {
+	params := handleRequestParams(c, resourceNameGateway)
+	dryRun, err := isDryRun(c)
+	if err != nil {
+		api.HandleBadRequest(c, err)
+		return
+	}
+	gateway := &apisv1.Gateway{}
+	err = c.ShouldBind(gateway)
+	if err != nil {
+		api.HandleBadRequest(c, err)
+		return
 	}
-	if _, ok := gateway.Labels[workingWorkspace]; !ok && params.Workspace != "" {
-		gateway.Labels[workingWorkspace] = params.Workspace
+	if params.ResourceName == "" {
+		params.ResourceName = gateway.Name
 	}
-	if _, ok := gateway.Labels[gatewayApiScope]; !ok && params.Scope != "" {
-		gateway.Labels[gatewayApiScope] = params.Scope
+
+	existing, err := h.getGateway(c.Request.Context(), params)
+	if err != nil {
+		api.HandleError(c, err)
+		return
 	}
 	if gateway.Namespace == "" {
-		gateway.Namespace = defaultWorkingNamespace
+		gateway.Namespace = existing.Namespace
+	}
+	if gateway.Namespace != existing.Namespace {
+		api.HandleBadRequest(c, field.Forbidden(field.NewPath("metadata", "namespace"), "the namespace of gateway can not be changed"))
+		return
+	}
+	err = keepScopeLabels(existing, gateway)
+	if err != nil {
+		api.HandleBadRequest(c, err)
+		return
+	}
+	// an update without resourceVersion would overwrite changes it has not seen
+	if gateway.ResourceVersion == "" {
+		api.HandleBadRequest(c, field.Required(field.NewPath("metadata", "resourceVersion"), "required to update the gateway"))
+		return
+	}
+	err = gatewayutil.DefaultAllowedRoutes(gateway)
+	if err != nil {
+		api.HandleBadRequest(c, err)
+		return
 	}
+	if h.updateGateway(c, gateway, dryRun) {
+		c.JSON(http.StatusOK, gateway)
+	}
+}
 
-	for _, listener := range gateway.Spec.Listeners {
-		if listener.AllowedRoutes == nil {
-			routes, err := h.newAllowedRoutesByGateway(c.Request.Context(), gateway)
-			if err != nil {
-				api.HandleError(c, err)
-				return
-			}
-			listener.AllowedRoutes = routes
+// updateGateway validates and updates the gateway, and syncs the certificates
+// issued for it. The error is written to the response, false is returned then.
+func (h *Handler) updateGateway(c *gin.Context, gateway *apisv1.Gateway, dryRun bool) bool {
+	warnings, err := h.writeGateway(c.Request.Context(), gateway, dryRun)
+	addWarnings(c, warnings)
+	if err != nil {
+		if errors.IsConflict(err) {
+			api.HandleConflict(c, err)
+			return false
 		}
+		api.HandleError(c, err)
+		return false
+	}
+	return true
+}
+
+// writeGateway is updateGateway which returns the warnings instead of writing
+// them, so that a check by dry run does not repeat the warnings of the update.
+func (h *Handler) writeGateway(ctx context.Context, gateway *apisv1.Gateway, dryRun bool) ([]string, error) {
+	certificates, err := wireCertificates(gateway)
+	if err == nil {
+		err = h.checkCertificates(ctx, gateway, certificates)
+	}
+	if err != nil {
+		return nil, err
+	}
+	warnings, err := h.checkGateway(ctx, gateway)
+	if err != nil {
+		return warnings, err
 	}
 
-	err = h.client.Create(c.Request.Context(), gateway)
+	var opts []rtclient.UpdateOption
+	if dryRun {
+		opts = append(opts, rtclient.DryRunAll)
+	}
+	err = h.client.Update(ctx, gateway, opts...)
+	if err != nil {
+		return warnings, err
+	}
+	if !dryRun {
+		warnings = append(warnings, h.syncCertificatesOrWarn(ctx, gateway, certificates)...)
+		h.revisions.record(ctx, gateway, kindGateway, OperationUpdate)
+	}
+	return warnings, nil
+}
+
+func (h *Handler) PatchGateway(c *gin.Context) {
+	params := handleRequestParams(c, resourceNameGateway)
+	dryRun, err := isDryRun(c)
+	if err != nil {
+		api.HandleBadRequest(c, err)
+		return
+	}
+	patch, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		api.HandleBadRequest(c, err)
+		return
+	}
+	fieldManager := c.Query("fieldManager")
+	if fieldManager == "" {
+		fieldManager = defaultFieldManager
+	}
+
+	existing, err := h.getGateway(c.Request.Context(), params)
 	if err != nil {
 		api.HandleError(c, err)
 		return
 	}
+
+	var gateway *apisv1.Gateway
+	var certificates []*unstructured.Unstructured
+	patchType := types.PatchType(c.ContentType())
+	switch patchType {
+	case types.MergePatchType, types.JSONPatchType:
+		var patched *apisv1.Gateway
+		patched, err = applyPatch(existing, patchType, patch)
+		if err != nil {
+			api.HandleBadRequest(c, err)
+			return
+		}
+		err = keepScopeLabels(existing, patched)
+		if err != nil {
+			api.HandleBadRequest(c, err)
+			return
+		}
+		err = gatewayutil.DefaultAllowedRoutes(patched)
+		if err != nil {
+			api.HandleBadRequest(c, err)
+			return
+		}
+		certificates, err = wireCertificates(patched)
+		if err == nil {
+			err = h.checkCertificates(c.Request.Context(), patched, certificates)
+		}
+		if err != nil {
+			api.HandleError(c, err)
+			return
+		}
+		err = h.validateGateway(c, patched)
+		if err != nil {
+			api.HandleError(c, err)
+			return
+		}
+		// the resourceVersion of existing gateway is kept unless the patch set it,
+		// so concurrent writes between the read and the update are rejected.
+		gateway = patched
+		opts := []rtclient.UpdateOption{rtclient.FieldOwner(fieldManager)}
+		if dryRun {
+			opts = append(opts, rtclient.DryRunAll)
+		}
+		err = h.client.Update(c.Request.Context(), gateway, opts...)
+	case types.ApplyPatchType:
+		// apply configuration is kept unstructured, so fields which the caller
+		// does not set are not claimed by its field manager.
+		applied := &unstructured.Unstructured{}
+		err = yaml.Unmarshal(patch, &applied.Object)
+		if err != nil {
+			api.HandleBadRequest(c, err)
+			return
+		}
+		if applied.GetName() != "" && applied.GetName() != existing.Name {
+			api.HandleBadRequest(c, field.Invalid(field.NewPath("metadata", "name"), applied.GetName(), fmt.Sprintf("must be %s", existing.Name)))
+			return
+		}
+		if applied.GetNamespace() != "" && applied.GetNamespace() != existing.Namespace {
+			api.HandleBadRequest(c, field.Forbidden(field.NewPath("metadata", "namespace"), "the namespace of gateway can not be changed"))
+			return
+		}
+		applied.SetName(existing.Name)
+		applied.SetNamespace(existing.Namespace)
+		applied.SetGroupVersionKind(apisv1.SchemeGroupVersion.WithKind(kindGateway))
+		err = keepScopeLabels(existing, applied)
+		if err != nil {
+			api.HandleBadRequest(c, err)
+			return
+		}
+		opts := []rtclient.PatchOption{rtclient.FieldOwner(fieldManager)}
+		if force, _ := strconv.ParseBool(c.Query("force")); force {
+			opts = append(opts, rtclient.ForceOwnership)
+		}
+		gateway, certificates, err = h.applyGateway(c, applied, dryRun, opts...)
+	default:
+		api.HandleUnsupportedMediaType(c, fmt.Errorf("unsupported patch type: %s", patchType))
+		return
+	}
+
+	if err != nil {
+		if errors.IsConflict(err) {
+			api.HandleConflict(c, err)
+			return
+		}
+		api.HandleError(c, err)
+		return
+	}
+	if !dryRun {
+		addWarnings(c, h.syncCertificatesOrWarn(c.Request.Context(), gateway, certificates))
+		h.revisions.record(c.Request.Context(), gateway, kindGateway, OperationUpdate)
+	}
 	c.JSON(http.StatusOK, gateway)
 }
 
-func (h *Handler) newAllowedRoutesByGateway(ctx context.Context, gateway *apisv1.Gateway) (*apisv1.AllowedRoutes, error) {
-	// TODO implement me!
-	return nil, nil
+// applyGateway applies the configuration of gateway. The result of apply is
+// known only after the write, so it is defaulted and validated by a dry run
+// first. The AllowedRoutes and certificateRefs which the apiserver defaults
+// are added to the configuration, so that the gateway is written by a single
+// apply.
+func (h *Handler) applyGateway(c *gin.Context, applied *unstructured.Unstructured, dryRun bool, opts ...rtclient.PatchOption) (*apisv1.Gateway, []*unstructured.Unstructured, error) {
+	ctx := c.Request.Context()
+	result := applied.DeepCopy()
+	err := h.client.Patch(ctx, result, rtclient.Apply, append(opts, rtclient.DryRunAll)...)
+	if err != nil {
+		return nil, nil, err
+	}
+	gateway, defaulted, certificates, err := h.defaultAppliedGateway(ctx, result)
+	if err == nil {
+		err = h.validateGateway(c, gateway)
+	}
+	if err != nil || dryRun {
+		return gateway, certificates, err
+	}
+
+	if defaulted {
+		err = applyListenerDefaults(applied, gateway)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	err = h.client.Patch(ctx, applied, rtclient.Apply, opts...)
+	if err != nil {
+		return nil, nil, err
+	}
+	gateway = &apisv1.Gateway{}
+	err = runtime.DefaultUnstructuredConverter.FromUnstructured(applied.Object, gateway)
+	return gateway, certificates, err
 }
 
-func (h *Handler) UpdateGateway(c *gin.Context) {
-	// TODO implement me!
+// applyListenerDefaults sets the AllowedRoutes and certificateRefs of the
+// listeners in the apply configuration to the defaulted ones of gateway, unless
+// the configuration sets them.
+func applyListenerDefaults(applied *unstructured.Unstructured, gateway *apisv1.Gateway) error {
+	listeners, found, err := unstructured.NestedSlice(applied.Object, "spec", "listeners")
+	if err != nil || !found {
+		return err
+	}
+	defaults := make(map[string]apisv1.Listener, len(gateway.Spec.Listeners))
+	for _, listener := range gateway.Spec.Listeners {
+		defaults[string(listener.Name)] = listener
+	}
+	for i := range listeners {
+		listener, ok := listeners[i].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		name, _ := listener["name"].(string)
+		defaulted, ok := defaults[name]
+		if !ok {
+			continue
+		}
+		if _, ok := listener["allowedRoutes"]; !ok && defaulted.AllowedRoutes != nil {
+			listener["allowedRoutes"], err = toUnstructuredValue(defaulted.AllowedRoutes)
+			if err != nil {
+				return err
+			}
+		}
+		if defaulted.TLS == nil || len(defaulted.TLS.CertificateRefs) == 0 {
+			continue
+		}
+		tls, ok := listener["tls"].(map[string]interface{})
+		if !ok {
+			listener["tls"], err = toUnstructuredValue(defaulted.TLS)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+		if _, ok := tls["certificateRefs"]; !ok {
+			tls["certificateRefs"], err = toUnstructuredValue(defaulted.TLS.CertificateRefs)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return unstructured.SetNestedSlice(applied.Object, listeners, "spec", "listeners")
+}
+
+// toUnstructuredValue converts the value to its form in unstructured objects.
+func toUnstructuredValue(value interface{}) (interface{}, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	var result interface{}
+	err = json.Unmarshal(data, &result)
+	return result, err
+}
+
+// defaultAppliedGateway returns the gateway of the result of apply with the
+// AllowedRoutes and certificateRefs defaulted, and whether any is defaulted.
+func (h *Handler) defaultAppliedGateway(ctx context.Context, applied *unstructured.Unstructured) (*apisv1.Gateway, bool, []*unstructured.Unstructured, error) {
+	gateway := &apisv1.Gateway{}
+	err := runtime.DefaultUnstructuredConverter.FromUnstructured(applied.Object, gateway)
+	if err != nil {
+		return nil, false, nil, err
+	}
+	spec := gateway.Spec.DeepCopy()
+	err = gatewayutil.DefaultAllowedRoutes(gateway)
+	if err != nil {
+		return nil, false, nil, errors.NewBadRequest(err.Error())
+	}
+	certificates, err := wireCertificates(gateway)
+	if err == nil {
+		err = h.checkCertificates(ctx, gateway, certificates)
+	}
+	if err != nil {
+		return nil, false, nil, err
+	}
+	return gateway, !equality.Semantic.DeepEqual(spec, &gateway.Spec), certificates, nil
+}
+
+// applyPatch applies a json merge patch or json patch to a copy of the gateway.
+func applyPatch(gateway *apisv1.Gateway, patchType types.PatchType, patch []byte) (*apisv1.Gateway, error) {
+	original, err := json.Marshal(gateway)
+	if err != nil {
+		return nil, err
+	}
+
+	var patched []byte
+	switch patchType {
+	case types.MergePatchType:
+		patched, err = jsonpatch.MergePatch(original, patch)
+	case types.JSONPatchType:
+		var p jsonpatch.Patch
+		p, err = jsonpatch.DecodePatch(patch)
+		if err == nil {
+			patched, err = p.Apply(original)
+		}
+	default:
+		err = fmt.Errorf("unsupported patch type: %s", patchType)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	result := &apisv1.Gateway{}
+	err = json.Unmarshal(patched, result)
+	if err != nil {
+		return nil, err
+	}
+	if result.Name != gateway.Name || result.Namespace != gateway.Namespace {
+		return nil, field.Forbidden(field.NewPath("metadata"), "the name and namespace of gateway can not be changed")
+	}
+	return result, nil
+}
+
+// keepScopeLabels carries the scope labels of the existing gateway over to the
+// updated one, and refuses any change that would move it to another scope.
+func keepScopeLabels(existing *apisv1.Gateway, gateway metav1.Object) error {
+	labels := gateway.GetLabels()
+	if labels == nil {
+		labels = map[string]string{}
+	}
+	for _, key := range gatewayutil.ScopeLabelKeys {
+		oldValue, found := existing.Labels[key]
+		value, ok := labels[key]
+		if !ok {
+			if found {
+				labels[key] = oldValue
+			}
+			continue
+		}
+		if !found || value != oldValue {
+			return field.Forbidden(field.NewPath("metadata", "labels").Key(key), "the scope label of gateway can not be changed")
+		}
+	}
+	gateway.SetLabels(labels)
+	return nil
 }
 
 func (h *Handler) DeleteGateway(c *gin.Context) {
@@ -178,7 +814,15 @@ func (h *Handler) DeleteGateway(c *gin.Context) {
 	err = h.client.Delete(c.Request.Context(), gateway)
 	if err != nil {
 		api.HandleError(c, err)
+		return
 	}
+	// the Certificates in other namespaces are not collected by owner
+	// references, the cleanup does not fail the deletion which is done
+	err = h.syncCertificates(c.Request.Context(), gateway, nil)
+	if err != nil {
+		klog.Errorf("clean up certificates of gateway %s/%s: %v", gateway.Namespace, gateway.Name, err)
+	}
+	h.revisions.record(c.Request.Context(), gateway, kindGateway, OperationDelete)
 	c.JSON(http.StatusOK, gin.H{"status": "success"})
 }
 
@@ -191,7 +835,7 @@ func (h *Handler) GetListeners(c *gin.Context) {
 		return
 	}
 
-	listener, err := parseListeners(gatewayClass)
+	listener, err := gatewayutil.ParseListeners(gatewayClass)
 	if err != nil {
 		api.HandleError(c, err)
 		return
@@ -201,73 +845,95 @@ func (h *Handler) GetListeners(c *gin.Context) {
 }
 
 func (h *Handler) GetGatewayClass(c *gin.Context) {
+	gatewayClassName := c.Param("gatewayclass")
+	gatewayClass := &apisv1.GatewayClass{}
+	err := h.client.Get(c.Request.Context(), types.NamespacedName{Name: gatewayClassName}, gatewayClass)
+	if err != nil {
+		api.HandleError(c, err)
+		return
+	}
 
+	c.JSON(http.StatusOK, newGatewayClass(gatewayClass))
 }
 
 func (h *Handler) ListGatewayClass(c *gin.Context) {
+	q := query.ParseQueryParameter(c)
+	selector, err := labels.Parse(q.LabelSelector)
+	if err != nil {
+		api.HandleBadRequest(c, err)
+		return
+	}
+	list := &apisv1.GatewayClassList{}
+	err = h.client.List(c.Request.Context(), list, rtclient.MatchingLabelsSelector{Selector: selector})
+	if err != nil {
+		api.HandleError(c, err)
+		return
+	}
 
+	items := make([]apisv1.GatewayClass, 0, len(list.Items))
+	for _, item := range list.Items {
+		if name := q.Filters[query.ParameterName]; name != "" && !strings.Contains(item.Name, name) {
+			continue
+		}
+		items = append(items, item)
+	}
+	sort.SliceStable(items, func(i, j int) bool {
+		return q.Compare(&items[i], &items[j])
+	})
+
+	start, end := q.Pagination.GetValidPagination(len(items))
+	classes := make([]GatewayClass, 0, end-start)
+	for i := start; i < end; i++ {
+		classes = append(classes, newGatewayClass(&items[i]))
+	}
+	c.JSON(http.StatusOK, api.ListResult{Items: classes, TotalItems: len(items)})
 }
 
-/*
-parseListeners get the listeners of GatewayClass.
-The gateway need to specific the listener information by annotations.
-
-Example:
-
-	apiVersion: gateway.networking.k8s.io/v1
-	kind: GatewayClass
-	metadata:
-	 annotations:
-	   gatewayapi.kubesphere.io/listener: web,websecure
-	   gatewayapi.kubesphere.io/listener.web.protocols: tcp,http
-	   gatewayapi.kubesphere.io/listener.web.port: '8000'
-	   gatewayapi.kubesphere.io/listener.websecure.protocols: tls,https
-	   gatewayapi.kubesphere.io/listener.websecure.port: '8443'
-	 name: traefik
-	spec:
-	 controllerName: traefik.io/gateway-controller
-*/
-func parseListeners(gatewayClass *apisv1.GatewayClass) ([]Listener, error) {
-	if gatewayClass.Annotations == nil {
-		return nil, fmt.Errorf("no listener can be used")
-	}
-	anno := gatewayClass.Annotations
-	listeners := make([]Listener, 0)
-	split := strings.Split(anno[gatewayListener], ",")
-	if len(split) == 0 {
-		return nil, fmt.Errorf("no listener can be used")
-	}
-
-	for _, l := range split {
-		listener := Listener{Name: l}
-		listener.Protocols = strings.Split(anno[fmt.Sprintf(gatewayListenerProtocol, l)], ",")
-		pStr := anno[fmt.Sprintf(gatewayListenerPort, l)]
-		if pStr != "" {
-			port, err := strconv.ParseInt(pStr, 10, 32)
-			if err != nil {
-				return nil, fmt.Errorf("invalid port: %s", err)
-			}
-			listener.Port = int32(port)
-		}
-		listeners = append(listeners, listener)
+// newGatewayClass summarizes the capabilities of GatewayClass, an invalid
+// listener annotation is reported as the error of the class.
+func newGatewayClass(gatewayClass *apisv1.GatewayClass) GatewayClass {
+	summary := GatewayClassSummary{
+		ControllerName:    string(gatewayClass.Spec.ControllerName),
+		ParametersRef:     gatewayClass.Spec.ParametersRef,
+		SupportedFeatures: gatewayClass.Status.SupportedFeatures,
+		Accepted:          meta.FindStatusCondition(gatewayClass.Status.Conditions, string(apisv1.GatewayClassConditionStatusAccepted)),
+	}
+	listeners, err := gatewayutil.ParseListeners(gatewayClass)
+	if err != nil {
+		summary.Error = err.Error()
+	} else {
+		summary.Listeners = listeners
+	}
+	return GatewayClass{GatewayClass: *gatewayClass, Summary: summary}
+}
+
+// scopeLabels returns the labels which identify the gateways of a scope.
+func scopeLabels(params ResourceParams) map[string]string {
+	labelMap := map[string]string{}
+	labelMap[gatewayutil.LabelScope] = params.Scope
+	if params.Workspace != "" {
+		labelMap[gatewayutil.LabelWorkingWorkspace] = params.Workspace
+	}
+	if params.Namespace != "" {
+		labelMap[gatewayutil.LabelWorkingNamespace] = params.Namespace
 	}
-	return listeners, nil
+	return labelMap
 }
 
 func handleRequestParams(c *gin.Context, resourceName string) ResourceParams {
 	s := ResourceParams{
-		Scope:        scopeCluster,
+		Scope:        gatewayutil.ScopeCluster,
 		ResourceName: c.Param(resourceName),
 	}
 	workspace := c.Param(paramWorkspace)
 	namespace := c.Param(paramNamespace)
 
 	if workspace != "" {
-		s.Scope = scopeWorkspace
+		s.Scope = gatewayutil.ScopeWorkspace
 		s.Workspace = workspace
 	}
 	if namespace != "" {
-		s.Scope = scopeNamespace
+		s.Scope = gatewayutil.ScopeNamespace
 		s.Namespace = namespace
 	}
 	return s
@@ -0,0 +1,356 @@
+package v1alpha1
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kubesphere-extensions/gateway-api/pkg/scheme"
+	"github.com/kubesphere-extensions/gateway-api/pkg/utils/gatewayutil"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	rtclient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+	apisv1 "sigs.k8s.io/gateway-api/apis/v1"
+)
+
+const testPrefix = "/kapis/gatewayapi.kubesphere.io/v1alpha1"
+
+// newTestServer serves the APIs by a fake client with the objects, the
+// requests are done as the apiserver without authorization.
+func newTestServer(t *testing.T, objects ...rtclient.Object) (*gin.Engine, rtclient.Client) {
+	return newInterceptedTestServer(t, interceptor.Funcs{}, objects...)
+}
+
+// newInterceptedTestServer is newTestServer whose client calls are
+// intercepted by funcs.
+func newInterceptedTestServer(t *testing.T, funcs interceptor.Funcs, objects ...rtclient.Object) (*gin.Engine, rtclient.Client) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	client := fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(objects...).
+		WithStatusSubresource(&apisv1.Gateway{}, &apisv1.HTTPRoute{}).WithInterceptorFuncs(funcs).Build()
+	engine := gin.New()
+	AddRouterGroup(engine, client, client, nil, nil)
+	return engine, client
+}
+
+// serve does the request with the body encoded in JSON unless it is a string.
+func serve(t *testing.T, engine *gin.Engine, method, path string, body interface{}) *httptest.ResponseRecorder {
+	t.Helper()
+	var data []byte
+	switch b := body.(type) {
+	case nil:
+	case string:
+		data = []byte(b)
+	default:
+		var err error
+		data, err = json.Marshal(body)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	req := httptest.NewRequest(method, testPrefix+path, bytes.NewReader(data))
+	req.Header.Set("Content-Type", "application/json")
+	recorder := httptest.NewRecorder()
+	engine.ServeHTTP(recorder, req)
+	return recorder
+}
+
+// statusOf decodes the Status of an error response.
+func statusOf(t *testing.T, recorder *httptest.ResponseRecorder) *metav1.Status {
+	t.Helper()
+	status := &metav1.Status{}
+	if err := json.Unmarshal(recorder.Body.Bytes(), status); err != nil {
+		t.Fatalf("decode status %q: %v", recorder.Body.String(), err)
+	}
+	return status
+}
+
+func testGatewayClass() *apisv1.GatewayClass {
+	return &apisv1.GatewayClass{
+		ObjectMeta: metav1.ObjectMeta{Name: "class"},
+		Spec:       apisv1.GatewayClassSpec{ControllerName: "example.com/gateway"},
+	}
+}
+
+func testGateway(namespace, name string, labels map[string]string) *apisv1.Gateway {
+	return &apisv1.Gateway{
+		ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name, Labels: labels},
+		Spec: apisv1.GatewaySpec{
+			GatewayClassName: "class",
+			Listeners:        []apisv1.Listener{{Name: "http", Protocol: apisv1.HTTPProtocolType, Port: 80}},
+		},
+	}
+}
+
+func TestCreateGatewayScope(t *testing.T) {
+	tests := []struct {
+		name      string
+		path      string
+		namespace string
+		labels    map[string]string
+		wantCode  int
+		// wantField is the field of the cause of rejection
+		wantField  string
+		wantLabels map[string]string
+	}{
+		{
+			name:     "namespace scope",
+			path:     "/namespaces/demo/gateways",
+			wantCode: http.StatusOK,
+			wantLabels: map[string]string{
+				gatewayutil.LabelScope:            gatewayutil.ScopeNamespace,
+				gatewayutil.LabelWorkingNamespace: "demo",
+			},
+		},
+		{
+			name:      "gateway in its working namespace",
+			path:      "/namespaces/demo/gateways",
+			namespace: "demo",
+			wantCode:  http.StatusOK,
+		},
+		{
+			name:      "working namespace of another namespace",
+			path:      "/namespaces/demo/gateways",
+			labels:    map[string]string{gatewayutil.LabelWorkingNamespace: "other"},
+			wantCode:  http.StatusUnprocessableEntity,
+			wantField: "metadata.labels[gatewayapi.kubesphere.io/working-namespace]",
+		},
+		{
+			name:      "cluster scope in a workspace",
+			path:      "/workspaces/ws/gateways",
+			labels:    map[string]string{gatewayutil.LabelScope: gatewayutil.ScopeCluster},
+			wantCode:  http.StatusUnprocessableEntity,
+			wantField: "metadata.labels[gatewayapi.kubesphere.io/scope]",
+		},
+		{
+			name:      "workspace label in cluster scope",
+			path:      "/gateways",
+			labels:    map[string]string{gatewayutil.LabelWorkingWorkspace: "ws"},
+			wantCode:  http.StatusUnprocessableEntity,
+			wantField: "metadata.labels[gatewayapi.kubesphere.io/working-workspace]",
+		},
+		{
+			name:      "another namespace",
+			path:      "/namespaces/demo/gateways",
+			namespace: "other",
+			wantCode:  http.StatusUnprocessableEntity,
+			wantField: "metadata.namespace",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			engine, client := newTestServer(t, testGatewayClass())
+			recorder := serve(t, engine, http.MethodPost, tt.path, testGateway(tt.namespace, "gateway", tt.labels))
+			if recorder.Code != tt.wantCode {
+				t.Fatalf("code = %d, want %d: %s", recorder.Code, tt.wantCode, recorder.Body.String())
+			}
+			if tt.wantField != "" {
+				status := statusOf(t, recorder)
+				if status.Details == nil || len(status.Details.Causes) == 0 || status.Details.Causes[0].Field != tt.wantField {
+					t.Errorf("causes = %+v, want field %s", status.Details, tt.wantField)
+				}
+				list := &apisv1.GatewayList{}
+				if err := client.List(context.Background(), list); err != nil || len(list.Items) != 0 {
+					t.Errorf("gateways = %v, %v, want none", list.Items, err)
+				}
+				return
+			}
+			namespace := tt.namespace
+			if namespace == "" {
+				namespace = defaultWorkingNamespace
+			}
+			gateway := &apisv1.Gateway{}
+			if err := client.Get(context.Background(), types.NamespacedName{Namespace: namespace, Name: "gateway"}, gateway); err != nil {
+				t.Fatal(err)
+			}
+			for key, value := range tt.wantLabels {
+				if gateway.Labels[key] != value {
+					t.Errorf("label %s = %q, want %q", key, gateway.Labels[key], value)
+				}
+			}
+		})
+	}
+}
+
+func TestUpdateGatewayConflicts(t *testing.T) {
+	scope := map[string]string{
+		gatewayutil.LabelScope:            gatewayutil.ScopeNamespace,
+		gatewayutil.LabelWorkingNamespace: "demo",
+	}
+	tests := []struct {
+		name            string
+		resourceVersion string
+		wantCode        int
+		wantField       string
+	}{
+		{
+			name:      "missing resourceVersion",
+			wantCode:  http.StatusUnprocessableEntity,
+			wantField: "metadata.resourceVersion",
+		},
+		{
+			name:            "stale resourceVersion",
+			resourceVersion: "1",
+			wantCode:        http.StatusConflict,
+		},
+		{
+			name:            "current resourceVersion",
+			resourceVersion: "999",
+			wantCode:        http.StatusOK,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			engine, _ := newTestServer(t, testGatewayClass(), testGateway("demo", "gw", scope))
+			gateway := testGateway("demo", "gw", scope)
+			gateway.ResourceVersion = tt.resourceVersion
+			recorder := serve(t, engine, http.MethodPut, "/namespaces/demo/gateways", gateway)
+			if recorder.Code != tt.wantCode {
+				t.Fatalf("code = %d, want %d: %s", recorder.Code, tt.wantCode, recorder.Body.String())
+			}
+			if tt.wantField != "" {
+				status := statusOf(t, recorder)
+				if status.Details == nil || len(status.Details.Causes) == 0 || status.Details.Causes[0].Field != tt.wantField {
+					t.Errorf("causes = %+v, want field %s", status.Details, tt.wantField)
+				}
+			}
+		})
+	}
+}
+
+func TestApplyGateway(t *testing.T) {
+	scope := map[string]string{
+		gatewayutil.LabelScope:            gatewayutil.ScopeNamespace,
+		gatewayutil.LabelWorkingNamespace: "demo",
+	}
+	// the apply is not supported by the fake client, it writes the
+	// configuration as is
+	var managers []string
+	var applied []*unstructured.Unstructured
+	var updates int
+	engine, _ := newInterceptedTestServer(t, interceptor.Funcs{
+		Patch: func(ctx context.Context, client rtclient.WithWatch, obj rtclient.Object, patch rtclient.Patch, opts ...rtclient.PatchOption) error {
+			patchOptions := &rtclient.PatchOptions{}
+			patchOptions.ApplyOptions(opts)
+			if len(patchOptions.DryRun) == 0 {
+				managers = append(managers, patchOptions.FieldManager)
+				applied = append(applied, obj.(*unstructured.Unstructured).DeepCopy())
+			}
+			return nil
+		},
+		Update: func(ctx context.Context, client rtclient.WithWatch, obj rtclient.Object, opts ...rtclient.UpdateOption) error {
+			updates++
+			return client.Update(ctx, obj, opts...)
+		},
+		List: func(ctx context.Context, client rtclient.WithWatch, list rtclient.ObjectList, opts ...rtclient.ListOption) error {
+			if _, ok := list.(*unstructured.UnstructuredList); ok {
+				return nil
+			}
+			return client.List(ctx, list, opts...)
+		},
+	}, testGatewayClass(), testGateway("demo", "gw", scope))
+
+	body := `{"apiVersion":"gateway.networking.k8s.io/v1","kind":"Gateway","metadata":{"name":"gw"},` +
+		`"spec":{"gatewayClassName":"class","listeners":[{"name":"http","protocol":"HTTP","port":80}]}}`
+	req := httptest.NewRequest(http.MethodPatch, testPrefix+"/namespaces/demo/gateways/gw", strings.NewReader(body))
+	req.Header.Set("Content-Type", string(types.ApplyPatchType))
+	recorder := httptest.NewRecorder()
+	engine.ServeHTTP(recorder, req)
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("code = %d: %s", recorder.Code, recorder.Body.String())
+	}
+
+	if len(applied) != 1 || updates != 0 {
+		t.Fatalf("applies = %d, updates = %d, want a single apply", len(applied), updates)
+	}
+	if managers[0] != defaultFieldManager {
+		t.Errorf("field manager = %q, want %q", managers[0], defaultFieldManager)
+	}
+	listeners, _, _ := unstructured.NestedSlice(applied[0].Object, "spec", "listeners")
+	if len(listeners) != 1 || listeners[0].(map[string]interface{})["allowedRoutes"] == nil {
+		t.Errorf("listeners = %v, want the defaulted allowedRoutes", listeners)
+	}
+}
+
+func TestListGateways(t *testing.T) {
+	cluster := map[string]string{gatewayutil.LabelScope: gatewayutil.ScopeCluster}
+	other := testGateway(defaultWorkingNamespace, "c", cluster)
+	other.Spec.GatewayClassName = "other"
+	objects := []rtclient.Object{
+		testGateway(defaultWorkingNamespace, "b", cluster),
+		testGateway(defaultWorkingNamespace, "a", cluster),
+		other,
+		testGateway(defaultWorkingNamespace, "d", map[string]string{
+			gatewayutil.LabelScope:            gatewayutil.ScopeNamespace,
+			gatewayutil.LabelWorkingNamespace: "demo",
+		}),
+	}
+	tests := []struct {
+		name      string
+		path      string
+		want      []string
+		wantTotal int
+	}{
+		{
+			name:      "sorted by name",
+			path:      "/gateways?sortBy=name&ascending=true",
+			want:      []string{"a", "b", "c"},
+			wantTotal: 3,
+		},
+		{
+			name:      "second page",
+			path:      "/gateways?sortBy=name&ascending=true&limit=2&page=2",
+			want:      []string{"c"},
+			wantTotal: 3,
+		},
+		{
+			name:      "name filter",
+			path:      "/gateways?name=b",
+			want:      []string{"b"},
+			wantTotal: 1,
+		},
+		{
+			name:      "gatewayClassName filter",
+			path:      "/gateways?gatewayClassName=other",
+			want:      []string{"c"},
+			wantTotal: 1,
+		},
+		{
+			name:      "namespace scope",
+			path:      "/namespaces/demo/gateways",
+			want:      []string{"d"},
+			wantTotal: 1,
+		},
+	}
+	engine, _ := newTestServer(t, objects...)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := serve(t, engine, http.MethodGet, tt.path, nil)
+			if recorder.Code != http.StatusOK {
+				t.Fatalf("code = %d: %s", recorder.Code, recorder.Body.String())
+			}
+			result := struct {
+				Items      []apisv1.Gateway `json:"items"`
+				TotalItems int              `json:"totalItems"`
+			}{}
+			if err := json.Unmarshal(recorder.Body.Bytes(), &result); err != nil {
+				t.Fatal(err)
+			}
+			got := make([]string, 0, len(result.Items))
+			for _, item := range result.Items {
+				got = append(got, item.Name)
+			}
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") || result.TotalItems != tt.wantTotal {
+				t.Errorf("items = %v (%d), want %v (%d)", got, result.TotalItems, tt.want, tt.wantTotal)
+			}
+		})
+	}
+}
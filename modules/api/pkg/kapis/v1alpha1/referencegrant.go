@@ -0,0 +1,288 @@
+package v1alpha1
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kubesphere-extensions/gateway-api/pkg/api"
+	"github.com/kubesphere-extensions/gateway-api/pkg/apiserver/query"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	rtclient "sigs.k8s.io/controller-runtime/pkg/client"
+	apisv1 "sigs.k8s.io/gateway-api/apis/v1"
+	"sigs.k8s.io/gateway-api/apis/v1beta1"
+)
+
+const (
+	resourceNameReferenceGrant = "referencegrant"
+
+	kindSecret  = "Secret"
+	kindService = "Service"
+)
+
+// reference is a reference from an object to an object in another namespace,
+// which is only allowed by a ReferenceGrant in the namespace of the referent.
+type reference struct {
+	fromGroup     string
+	fromKind      string
+	fromNamespace string
+
+	toGroup     string
+	toKind      string
+	toNamespace string
+	toName      string
+}
+
+func (h *Handler) ListReferenceGrants(c *gin.Context) {
+	params := handleRequestParams(c, resourceNameReferenceGrant)
+	q := query.ParseQueryParameter(c)
+	selector, err := labels.Parse(q.LabelSelector)
+	if err != nil {
+		api.HandleBadRequest(c, err)
+		return
+	}
+	list := &v1beta1.ReferenceGrantList{}
+	err = h.client.List(c.Request.Context(), list, rtclient.MatchingLabelsSelector{Selector: selector}, rtclient.InNamespace(params.Namespace))
+	if err != nil {
+		api.HandleError(c, err)
+		return
+	}
+
+	items := make([]v1beta1.ReferenceGrant, 0, len(list.Items))
+	for _, item := range list.Items {
+		if name := q.Filters[query.ParameterName]; name != "" && !strings.Contains(item.Name, name) {
+			continue
+		}
+		items = append(items, item)
+	}
+	sort.SliceStable(items, func(i, j int) bool {
+		return q.Compare(&items[i], &items[j])
+	})
+
+	start, end := q.Pagination.GetValidPagination(len(items))
+	c.JSON(http.StatusOK, api.ListResult{Items: items[start:end], TotalItems: len(items)})
+}
+
+func (h *Handler) CreateReferenceGrant(c *gin.Context) {
+	params := handleRequestParams(c, resourceNameReferenceGrant)
+	grant := &v1beta1.ReferenceGrant{}
+	err := c.ShouldBind(grant)
+	if err != nil {
+		api.HandleBadRequest(c, err)
+		return
+	}
+	if grant.Namespace == "" {
+		grant.Namespace = params.Namespace
+	}
+	if grant.Namespace != params.Namespace {
+		api.HandleBadRequest(c, field.Invalid(field.NewPath("metadata", "namespace"), grant.Namespace, fmt.Sprintf("must be %s", params.Namespace)))
+		return
+	}
+
+	err = h.client.Create(c.Request.Context(), grant)
+	if err != nil {
+		api.HandleError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, grant)
+}
+
+func (h *Handler) DeleteReferenceGrant(c *gin.Context) {
+	params := handleRequestParams(c, resourceNameReferenceGrant)
+	grant := &v1beta1.ReferenceGrant{}
+	err := h.client.Get(c.Request.Context(), types.NamespacedName{Namespace: params.Namespace, Name: params.ResourceName}, grant)
+	if err != nil {
+		api.HandleError(c, err)
+		return
+	}
+	err = h.client.Delete(c.Request.Context(), grant)
+	if err != nil {
+		api.HandleError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "success"})
+}
+
+// GetGatewayMissingReferenceGrants returns the ReferenceGrants which the
+// certificateRefs of gateway listeners need but do not exist yet.
+func (h *Handler) GetGatewayMissingReferenceGrants(c *gin.Context) {
+	params := handleRequestParams(c, resourceNameGateway)
+	gateway, err := h.getGateway(c.Request.Context(), params)
+	if err != nil {
+		api.HandleError(c, err)
+		return
+	}
+
+	grants, err := missingReferenceGrants(c.Request.Context(), h.client, gatewayReferences(gateway))
+	if err != nil {
+		api.HandleError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, api.ListResult{Items: grants, TotalItems: len(grants)})
+}
+
+// GetMissingReferenceGrants returns the ReferenceGrants which the backendRefs
+// of route need but do not exist yet.
+func (h *RouteHandler) GetMissingReferenceGrants(c *gin.Context) {
+	params := handleRequestParams(c, resourceNameRoute)
+	route := h.kind.newObject()
+	err := h.client.Get(c.Request.Context(), types.NamespacedName{Namespace: params.Namespace, Name: params.ResourceName}, route)
+	if err != nil {
+		api.HandleError(c, err)
+		return
+	}
+
+	grants, err := missingReferenceGrants(c.Request.Context(), h.client, h.kind.references(route))
+	if err != nil {
+		api.HandleError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, api.ListResult{Items: grants, TotalItems: len(grants)})
+}
+
+// gatewayReferences returns the cross namespace certificateRefs of gateway.
+func gatewayReferences(gateway *apisv1.Gateway) []reference {
+	refs := make([]reference, 0)
+	for _, listener := range gateway.Spec.Listeners {
+		if listener.TLS == nil {
+			continue
+		}
+		for _, ref := range listener.TLS.CertificateRefs {
+			r := reference{
+				fromGroup:     apisv1.GroupName,
+				fromKind:      kindGateway,
+				fromNamespace: gateway.Namespace,
+				toKind:        kindSecret,
+				toNamespace:   gateway.Namespace,
+				toName:        string(ref.Name),
+			}
+			if ref.Group != nil {
+				r.toGroup = string(*ref.Group)
+			}
+			if ref.Kind != nil {
+				r.toKind = string(*ref.Kind)
+			}
+			if ref.Namespace != nil {
+				r.toNamespace = string(*ref.Namespace)
+			}
+			if r.toNamespace != r.fromNamespace {
+				refs = append(refs, r)
+			}
+		}
+	}
+	return refs
+}
+
+// references returns the cross namespace backendRefs of route.
+func (k routeKind) references(route rtclient.Object) []reference {
+	refs := make([]reference, 0)
+	for _, ref := range k.backendRefs(route) {
+		r := reference{
+			fromGroup:     k.gvk.Group,
+			fromKind:      k.gvk.Kind,
+			fromNamespace: route.GetNamespace(),
+			toKind:        kindService,
+			toNamespace:   route.GetNamespace(),
+			toName:        string(ref.Name),
+		}
+		if ref.Group != nil {
+			r.toGroup = string(*ref.Group)
+		}
+		if ref.Kind != nil {
+			r.toKind = string(*ref.Kind)
+		}
+		if ref.Namespace != nil {
+			r.toNamespace = string(*ref.Namespace)
+		}
+		if r.toNamespace != r.fromNamespace {
+			refs = append(refs, r)
+		}
+	}
+	return refs
+}
+
+// missingReferenceGrants returns the ReferenceGrants which would allow the
+// references not allowed by any existing ReferenceGrant, one grant is returned
+// for each referent namespace and referrer kind and namespace.
+func missingReferenceGrants(ctx context.Context, client rtclient.Client, refs []reference) ([]v1beta1.ReferenceGrant, error) {
+	existing := map[string][]v1beta1.ReferenceGrant{}
+	missing := map[string]*v1beta1.ReferenceGrant{}
+	keys := make([]string, 0)
+	for _, ref := range refs {
+		grants, ok := existing[ref.toNamespace]
+		if !ok {
+			list := &v1beta1.ReferenceGrantList{}
+			err := client.List(ctx, list, rtclient.InNamespace(ref.toNamespace))
+			if err != nil {
+				return nil, err
+			}
+			grants = list.Items
+			existing[ref.toNamespace] = grants
+		}
+		if referenceGranted(ref, grants) {
+			continue
+		}
+
+		key := strings.Join([]string{ref.toNamespace, ref.fromGroup, ref.fromKind, ref.fromNamespace}, "/")
+		grant, ok := missing[key]
+		if !ok {
+			grant = &v1beta1.ReferenceGrant{}
+			grant.SetGroupVersionKind(v1beta1.SchemeGroupVersion.WithKind("ReferenceGrant"))
+			grant.Namespace = ref.toNamespace
+			// a fixed name could replace an existing grant when the suggestion is applied
+			grant.GenerateName = fmt.Sprintf("allow-%s-from-%s-", strings.ToLower(ref.fromKind), ref.fromNamespace)
+			grant.Spec.From = []v1beta1.ReferenceGrantFrom{{
+				Group:     v1beta1.Group(ref.fromGroup),
+				Kind:      v1beta1.Kind(ref.fromKind),
+				Namespace: v1beta1.Namespace(ref.fromNamespace),
+			}}
+			missing[key] = grant
+			keys = append(keys, key)
+		}
+		name := v1beta1.ObjectName(ref.toName)
+		to := v1beta1.ReferenceGrantTo{Group: v1beta1.Group(ref.toGroup), Kind: v1beta1.Kind(ref.toKind), Name: &name}
+		duplicated := false
+		for _, t := range grant.Spec.To {
+			if t.Group == to.Group && t.Kind == to.Kind && *t.Name == *to.Name {
+				duplicated = true
+				break
+			}
+		}
+		if !duplicated {
+			grant.Spec.To = append(grant.Spec.To, to)
+		}
+	}
+
+	grants := make([]v1beta1.ReferenceGrant, 0, len(keys))
+	for _, key := range keys {
+		grants = append(grants, *missing[key])
+	}
+	return grants, nil
+}
+
+// referenceGranted reports whether any of the grants allows the reference.
+func referenceGranted(ref reference, grants []v1beta1.ReferenceGrant) bool {
+	for _, grant := range grants {
+		fromMatched := false
+		for _, from := range grant.Spec.From {
+			if string(from.Group) == ref.fromGroup && string(from.Kind) == ref.fromKind && string(from.Namespace) == ref.fromNamespace {
+				fromMatched = true
+				break
+			}
+		}
+		if !fromMatched {
+			continue
+		}
+		for _, to := range grant.Spec.To {
+			if string(to.Group) == ref.toGroup && string(to.Kind) == ref.toKind && (to.Name == nil || string(*to.Name) == ref.toName) {
+				return true
+			}
+		}
+	}
+	return false
+}
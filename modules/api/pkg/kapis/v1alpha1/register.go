@@ -2,33 +2,102 @@ package v1alpha1
 
 import (
 	"github.com/gin-gonic/gin"
+	"github.com/kubesphere-extensions/gateway-api/pkg/apiserver/authorization"
 	apiruntime "github.com/kubesphere-extensions/gateway-api/pkg/apiserver/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
 	rtclient "sigs.k8s.io/controller-runtime/pkg/client"
 )
 
-func AddRouterGroup(engin *gin.Engine, client rtclient.Client) {
+// FilteredResources are the resources whose cluster scoped lists only return
+// the items which the caller may see, instead of being forbidden.
+var FilteredResources = []string{resourceGateways}
+
+// AddRouterGroup registers the APIs, the watches are served by the shared
+// informers if informers is not nil. serviceClient does the requests which the
+// callers are not allowed to do themselves as the apiserver.
+func AddRouterGroup(engin *gin.Engine, client, serviceClient rtclient.Client, authorizer authorization.Authorizer, informers cache.Informers, middlewares ...gin.HandlerFunc) {
 	group := apiruntime.NewRouterGroup("gatewayapi.kubesphere.io", "v1alpha1", engin)
-	handler := NewHandler(client)
+	group.Use(middlewares...)
+	handler := NewHandler(client, serviceClient, authorizer, informers)
 
 	group.GET("/gateways/:gateway", handler.GetGateway)
 	group.GET("/gateways", handler.ListGateways)
 	group.POST("/gateways", handler.CreateGateway)
+	group.POST("/gateways/from-template", handler.CreateGatewayFromTemplate)
 	group.PUT("/gateways", handler.UpdateGateway)
+	group.PATCH("/gateways/:gateway", handler.PatchGateway)
 	group.DELETE("/gateways/:gateway", handler.DeleteGateway)
+	group.GET("/gateways/:gateway/status", handler.GetGatewayStatus)
+	group.GET("/gateways/:gateway/routes", handler.ListGatewayRoutes)
+	group.GET("/gateways/:gateway/certificates", handler.GetGatewayCertificates)
+	group.POST("/gateways/:gateway/certificates", handler.UploadGatewayCertificate)
+	group.GET("/gateways/:gateway/missing-referencegrants", handler.GetGatewayMissingReferenceGrants)
+	group.POST("/gateways/:gateway/validate", handler.ValidateGateway)
+	group.GET("/gateways/:gateway/revisions", handler.ListGatewayRevisions)
+	group.POST("/gateways/:gateway/revisions/:revision/restore", handler.RestoreGatewayRevision)
 
 	group.GET("/workspaces/:workspace/gateways/:gateway", handler.GetGateway)
 	group.GET("/workspaces/:workspace/gateways", handler.ListGateways)
 	group.POST("/workspaces/:workspace/gateways", handler.CreateGateway)
+	group.POST("/workspaces/:workspace/gateways/from-template", handler.CreateGatewayFromTemplate)
 	group.PUT("/workspaces/:workspace/gateways", handler.UpdateGateway)
+	group.PATCH("/workspaces/:workspace/gateways/:gateway", handler.PatchGateway)
 	group.DELETE("/workspaces/:workspace/gateways/:gateway", handler.DeleteGateway)
+	group.GET("/workspaces/:workspace/gateways/:gateway/status", handler.GetGatewayStatus)
+	group.GET("/workspaces/:workspace/gateways/:gateway/routes", handler.ListGatewayRoutes)
+	group.GET("/workspaces/:workspace/gateways/:gateway/certificates", handler.GetGatewayCertificates)
+	group.POST("/workspaces/:workspace/gateways/:gateway/certificates", handler.UploadGatewayCertificate)
+	group.GET("/workspaces/:workspace/gateways/:gateway/missing-referencegrants", handler.GetGatewayMissingReferenceGrants)
+	group.POST("/workspaces/:workspace/gateways/:gateway/validate", handler.ValidateGateway)
+	group.GET("/workspaces/:workspace/gateways/:gateway/revisions", handler.ListGatewayRevisions)
+	group.POST("/workspaces/:workspace/gateways/:gateway/revisions/:revision/restore", handler.RestoreGatewayRevision)
 
 	group.GET("/namespaces/:namespace/gateways/:gateway", handler.GetGateway)
 	group.GET("/namespaces/:namespace/gateways", handler.ListGateways)
 	group.POST("/namespaces/:namespace/gateways", handler.CreateGateway)
+	group.POST("/namespaces/:namespace/gateways/from-template", handler.CreateGatewayFromTemplate)
 	group.PUT("/namespaces/:namespace/gateways", handler.UpdateGateway)
+	group.PATCH("/namespaces/:namespace/gateways/:gateway", handler.PatchGateway)
 	group.DELETE("/namespaces/:namespace/gateways/:gateway", handler.DeleteGateway)
+	group.GET("/namespaces/:namespace/gateways/:gateway/status", handler.GetGatewayStatus)
+	group.GET("/namespaces/:namespace/gateways/:gateway/routes", handler.ListGatewayRoutes)
+	group.GET("/namespaces/:namespace/gateways/:gateway/certificates", handler.GetGatewayCertificates)
+	group.POST("/namespaces/:namespace/gateways/:gateway/certificates", handler.UploadGatewayCertificate)
+	group.GET("/namespaces/:namespace/gateways/:gateway/missing-referencegrants", handler.GetGatewayMissingReferenceGrants)
+	group.POST("/namespaces/:namespace/gateways/:gateway/validate", handler.ValidateGateway)
+	group.GET("/namespaces/:namespace/gateways/:gateway/revisions", handler.ListGatewayRevisions)
+	group.POST("/namespaces/:namespace/gateways/:gateway/revisions/:revision/restore", handler.RestoreGatewayRevision)
 
 	group.GET("/gatewayclasses", handler.ListGatewayClass)
 	group.GET("/gatewayclasses/:gatewayclass", handler.GetGatewayClass)
 	group.GET("/gatewayclasses/:gatewayclass/listeners", handler.GetListeners)
+
+	group.GET("/namespaces/:namespace/referencegrants", handler.ListReferenceGrants)
+	group.POST("/namespaces/:namespace/referencegrants", handler.CreateReferenceGrant)
+	group.DELETE("/namespaces/:namespace/referencegrants/:referencegrant", handler.DeleteReferenceGrant)
+
+	for _, kind := range routeKinds {
+		routeHandler := NewRouteHandler(client, serviceClient, kind, informers)
+
+		group.GET("/workspaces/:workspace/"+kind.resource, routeHandler.ListRoutes)
+
+		group.GET("/namespaces/:namespace/"+kind.resource+"/:route", routeHandler.GetRoute)
+		group.GET("/namespaces/:namespace/"+kind.resource, routeHandler.ListRoutes)
+		group.POST("/namespaces/:namespace/"+kind.resource, routeHandler.CreateRoute)
+		group.PUT("/namespaces/:namespace/"+kind.resource+"/:route", routeHandler.UpdateRoute)
+		group.DELETE("/namespaces/:namespace/"+kind.resource+"/:route", routeHandler.DeleteRoute)
+		group.GET("/namespaces/:namespace/"+kind.resource+"/:route/missing-referencegrants", routeHandler.GetMissingReferenceGrants)
+		group.GET("/namespaces/:namespace/"+kind.resource+"/:route/revisions", routeHandler.ListRouteRevisions)
+		group.POST("/namespaces/:namespace/"+kind.resource+"/:route/revisions/:revision/restore", routeHandler.RestoreRouteRevision)
+
+		if kind.resource == httpRouteKind.resource || kind.resource == grpcRouteKind.resource {
+			group.POST("/namespaces/:namespace/"+kind.resource+"/:route/switch", routeHandler.SwitchBackends)
+			group.POST("/namespaces/:namespace/"+kind.resource+"/:route/rollback", routeHandler.RollbackRoute)
+		}
+		if kind.resource == httpRouteKind.resource {
+			group.GET("/namespaces/:namespace/"+kind.resource+"/:route/canary", routeHandler.GetCanary)
+			group.PUT("/namespaces/:namespace/"+kind.resource+"/:route/canary", routeHandler.PutCanary)
+			group.DELETE("/namespaces/:namespace/"+kind.resource+"/:route/canary", routeHandler.DeleteCanary)
+		}
+	}
 }
@@ -0,0 +1,476 @@
+package v1alpha1
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"net/http"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	jsonpatch "github.com/evanphx/json-patch/v5"
+	"github.com/gin-gonic/gin"
+	"github.com/kubesphere-extensions/gateway-api/pkg/api"
+	"github.com/kubesphere-extensions/gateway-api/pkg/apiserver/request"
+	"github.com/kubesphere-extensions/gateway-api/pkg/utils/gatewayutil"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/client-go/util/retry"
+	"k8s.io/klog/v2"
+	rtclient "sigs.k8s.io/controller-runtime/pkg/client"
+	apisv1 "sigs.k8s.io/gateway-api/apis/v1"
+)
+
+const (
+	// LabelRevisionKind is the kind of object whose revisions a ConfigMap holds.
+	LabelRevisionKind = "gatewayapi.kubesphere.io/revision-kind"
+	// AnnotationRevisionOf is the name of object whose revisions a ConfigMap
+	// holds, the name may be too long for a label.
+	AnnotationRevisionOf = "gatewayapi.kubesphere.io/revision-of"
+
+	OperationCreate = "Create"
+	OperationUpdate = "Update"
+	OperationDelete = "Delete"
+
+	paramRevision = "revision"
+
+	// maxRevisions is the number of revisions kept for an object
+	maxRevisions = 10
+	// maxRevisionsSize bounds the data of ConfigMap below the size limit of
+	// objects, the oldest revisions are dropped beyond it
+	maxRevisionsSize = 900 * 1024
+)
+
+// revisionIgnoredAnnotations are not kept by revisions, they are either large
+// or the state of an operation which a restore must not bring back.
+var revisionIgnoredAnnotations = []string{
+	corev1.LastAppliedConfigAnnotation,
+	gatewayutil.AnnotationCanary,
+	AnnotationPreviousSpec,
+}
+
+// Revision is a change of the spec of an object made through the API.
+type Revision struct {
+	Revision  int         `json:"revision"`
+	Operation string      `json:"operation"`
+	Author    string      `json:"author,omitempty"`
+	Timestamp metav1.Time `json:"timestamp"`
+	// Diff is the JSON merge patch from the spec of previous revision, it is
+	// empty for the first revision and deletions
+	Diff json.RawMessage `json:"diff,omitempty"`
+	Spec json.RawMessage `json:"spec"`
+	// Labels and Annotations are the metadata of object, which are used when
+	// a deleted object is restored
+	Labels      map[string]string `json:"labels,omitempty"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+// revisionHistory keeps the bounded revision history of each object in a
+// ConfigMap next to it. The ConfigMaps are written as the apiserver, since the
+// callers may not write the ConfigMaps in the namespace of gateways, and they
+// outlive the objects so that deleted objects can be restored.
+type revisionHistory struct {
+	client rtclient.Client
+}
+
+// record adds a revision of the object if its spec is changed or it is
+// deleted. The change is already written, so a failure is only logged.
+func (r *revisionHistory) record(ctx context.Context, object rtclient.Object, kind, operation string) {
+	err := r.add(ctx, object, kind, operation)
+	if err != nil {
+		klog.Errorf("record revision of %s %s/%s: %v", kind, object.GetNamespace(), object.GetName(), err)
+	}
+}
+
+func (r *revisionHistory) add(ctx context.Context, object rtclient.Object, kind, operation string) error {
+	spec, err := specOf(object)
+	if err != nil {
+		return err
+	}
+	revision := Revision{
+		Operation:   operation,
+		Timestamp:   metav1.NewTime(time.Now()),
+		Spec:        spec,
+		Labels:      object.GetLabels(),
+		Annotations: revisionAnnotations(object),
+	}
+	if user, ok := request.UserFrom(ctx); ok {
+		revision.Author = user.Username
+	}
+
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		configMap := &corev1.ConfigMap{}
+		err := r.client.Get(ctx, types.NamespacedName{Namespace: object.GetNamespace(), Name: revisionsName(kind, object.GetName())}, configMap)
+		switch {
+		case errors.IsNotFound(err):
+			configMap = newRevisionsConfigMap(object, kind)
+		case err != nil:
+			return err
+		case !holdsRevisionsOf(configMap, scopeLabelsOf(object), kind, object.GetName()):
+			// the ConfigMap of the same name is not written by revisions
+			return fmt.Errorf("ConfigMap %s/%s does not hold the revisions of %s %s", configMap.Namespace, configMap.Name, kind, object.GetName())
+		}
+		revisions, err := revisionsOf(configMap)
+		if err != nil {
+			return err
+		}
+
+		revision.Revision = 1
+		revision.Diff = nil
+		if len(revisions) != 0 {
+			latest := revisions[0]
+			revision.Revision = latest.Revision + 1
+			if operation != OperationDelete && latest.Operation != OperationDelete {
+				if jsonEqual(latest.Spec, spec) {
+					return nil
+				}
+				revision.Diff, err = jsonpatch.CreateMergePatch(latest.Spec, spec)
+				if err != nil {
+					return err
+				}
+			}
+		}
+		data, err := json.Marshal(revision)
+		if err != nil {
+			return err
+		}
+		if configMap.Data == nil {
+			configMap.Data = map[string]string{}
+		}
+		configMap.Data[strconv.Itoa(revision.Revision)] = string(data)
+		size := len(data)
+		for i := range revisions {
+			key := strconv.Itoa(revisions[i].Revision)
+			size += len(configMap.Data[key])
+			if i >= maxRevisions-1 || size > maxRevisionsSize {
+				delete(configMap.Data, key)
+			}
+		}
+
+		if configMap.ResourceVersion == "" {
+			return r.client.Create(ctx, configMap)
+		}
+		return r.client.Update(ctx, configMap)
+	})
+}
+
+// find returns the revisions of the object in namespace, newest first. The
+// ConfigMap is only looked up by its name in the namespace of object, and the
+// revisions of a gateway are only found in the scope of request.
+func (r *revisionHistory) find(ctx context.Context, namespace string, scope map[string]string, kind, name string) ([]Revision, error) {
+	configMap := &corev1.ConfigMap{}
+	err := r.client.Get(ctx, types.NamespacedName{Namespace: namespace, Name: revisionsName(kind, name)}, configMap)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return []Revision{}, nil
+		}
+		return nil, err
+	}
+	if !holdsRevisionsOf(configMap, scope, kind, name) {
+		return []Revision{}, nil
+	}
+	return revisionsOf(configMap)
+}
+
+// holdsRevisionsOf reports whether the ConfigMap holds the revisions of the
+// object of kind and name in the scope.
+func holdsRevisionsOf(configMap *corev1.ConfigMap, scope map[string]string, kind, name string) bool {
+	if configMap.Labels[LabelRevisionKind] != kind || configMap.Annotations[AnnotationRevisionOf] != name {
+		return false
+	}
+	for k, v := range scope {
+		if configMap.Labels[k] != v {
+			return false
+		}
+	}
+	return true
+}
+
+// scopeLabelsOf returns the scope labels of object.
+func scopeLabelsOf(object rtclient.Object) map[string]string {
+	labels := map[string]string{}
+	for _, key := range gatewayutil.ScopeLabelKeys {
+		if value, ok := object.GetLabels()[key]; ok {
+			labels[key] = value
+		}
+	}
+	return labels
+}
+
+// revisionAnnotations returns the annotations of object kept by revisions.
+func revisionAnnotations(object rtclient.Object) map[string]string {
+	var annotations map[string]string
+	for k, v := range object.GetAnnotations() {
+		if slices.Contains(revisionIgnoredAnnotations, k) {
+			continue
+		}
+		if annotations == nil {
+			annotations = map[string]string{}
+		}
+		annotations[k] = v
+	}
+	return annotations
+}
+
+func newRevisionsConfigMap(object rtclient.Object, kind string) *corev1.ConfigMap {
+	labels := scopeLabelsOf(object)
+	labels[LabelRevisionKind] = kind
+	return &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:   object.GetNamespace(),
+			Name:        revisionsName(kind, object.GetName()),
+			Labels:      labels,
+			Annotations: map[string]string{AnnotationRevisionOf: object.GetName()},
+		},
+	}
+}
+
+// revisionsName returns the name of ConfigMap of the revisions, the name of
+// long object is shortened with its hash.
+func revisionsName(kind, name string) string {
+	full := fmt.Sprintf("%s-%s-revisions", strings.ToLower(kind), name)
+	if len(full) <= validation.DNS1123SubdomainMaxLength {
+		return full
+	}
+	hash := fnv.New32a()
+	_, _ = hash.Write([]byte(full))
+	return fmt.Sprintf("%s-%08x", full[:validation.DNS1123SubdomainMaxLength-9], hash.Sum32())
+}
+
+// revisionsOf returns the revisions in ConfigMap, newest first.
+func revisionsOf(configMap *corev1.ConfigMap) ([]Revision, error) {
+	revisions := make([]Revision, 0, len(configMap.Data))
+	for key, value := range configMap.Data {
+		revision := Revision{}
+		if err := json.Unmarshal([]byte(value), &revision); err != nil {
+			return nil, fmt.Errorf("invalid revision %s of ConfigMap %s/%s: %v", key, configMap.Namespace, configMap.Name, err)
+		}
+		revisions = append(revisions, revision)
+	}
+	sort.Slice(revisions, func(i, j int) bool {
+		return revisions[i].Revision > revisions[j].Revision
+	})
+	return revisions, nil
+}
+
+// revisionOf returns the revision of request.
+func revisionOf(c *gin.Context, revisions []Revision) (*Revision, error) {
+	number, err := strconv.Atoi(c.Param(paramRevision))
+	if err != nil {
+		return nil, errors.NewBadRequest(fmt.Sprintf("invalid revision %q", c.Param(paramRevision)))
+	}
+	for i := range revisions {
+		if revisions[i].Revision == number {
+			return &revisions[i], nil
+		}
+	}
+	return nil, errors.NewNotFound(schema.GroupResource{Resource: "revisions"}, c.Param(paramRevision))
+}
+
+// specOf returns the spec of object in JSON.
+func specOf(object rtclient.Object) (json.RawMessage, error) {
+	content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(object)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(content["spec"])
+}
+
+// setSpec replaces the spec of object.
+func setSpec(object rtclient.Object, spec json.RawMessage) error {
+	content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(object)
+	if err != nil {
+		return err
+	}
+	var value interface{}
+	if err = json.Unmarshal(spec, &value); err != nil {
+		return err
+	}
+	content["spec"] = value
+	return runtime.DefaultUnstructuredConverter.FromUnstructured(content, object)
+}
+
+func jsonEqual(a, b []byte) bool {
+	var compactA, compactB bytes.Buffer
+	if json.Compact(&compactA, a) != nil || json.Compact(&compactB, b) != nil {
+		return bytes.Equal(a, b)
+	}
+	return bytes.Equal(compactA.Bytes(), compactB.Bytes())
+}
+
+// gatewayNamespace returns the namespace of gateway, which is the default
+// working namespace if the gateway is deleted.
+func (h *Handler) gatewayNamespace(ctx context.Context, params ResourceParams) (string, *apisv1.Gateway, error) {
+	gateway, err := h.getGateway(ctx, params)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return defaultWorkingNamespace, nil, nil
+		}
+		return "", nil, err
+	}
+	return gateway.Namespace, gateway, nil
+}
+
+func (h *Handler) ListGatewayRevisions(c *gin.Context) {
+	params := handleRequestParams(c, resourceNameGateway)
+	ctx := c.Request.Context()
+	namespace, _, err := h.gatewayNamespace(ctx, params)
+	if err != nil {
+		api.HandleError(c, err)
+		return
+	}
+	revisions, err := h.revisions.find(ctx, namespace, scopeLabels(params), kindGateway, params.ResourceName)
+	if err != nil {
+		api.HandleError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, api.ListResult{Items: revisions, TotalItems: len(revisions)})
+}
+
+// RestoreGatewayRevision restores the spec of gateway to the revision, the
+// deleted gateway is created again.
+func (h *Handler) RestoreGatewayRevision(c *gin.Context) {
+	params := handleRequestParams(c, resourceNameGateway)
+	ctx := c.Request.Context()
+	namespace, gateway, err := h.gatewayNamespace(ctx, params)
+	if err != nil {
+		api.HandleError(c, err)
+		return
+	}
+	revisions, err := h.revisions.find(ctx, namespace, scopeLabels(params), kindGateway, params.ResourceName)
+	if err != nil {
+		api.HandleError(c, err)
+		return
+	}
+	revision, err := revisionOf(c, revisions)
+	if err != nil {
+		api.HandleError(c, err)
+		return
+	}
+
+	if gateway == nil {
+		gateway = &apisv1.Gateway{ObjectMeta: metav1.ObjectMeta{
+			Namespace:   namespace,
+			Name:        params.ResourceName,
+			Labels:      revision.Labels,
+			Annotations: revision.Annotations,
+		}}
+		if err = setSpec(gateway, revision.Spec); err != nil {
+			api.HandleError(c, err)
+			return
+		}
+		h.createGateway(c, params, gateway)
+		return
+	}
+
+	dryRun, err := isDryRun(c)
+	if err != nil {
+		api.HandleBadRequest(c, err)
+		return
+	}
+	if err = setSpec(gateway, revision.Spec); err != nil {
+		api.HandleError(c, err)
+		return
+	}
+	if h.updateGateway(c, gateway, dryRun) {
+		c.JSON(http.StatusOK, gateway)
+	}
+}
+
+func (h *RouteHandler) ListRouteRevisions(c *gin.Context) {
+	params := handleRequestParams(c, resourceNameRoute)
+	revisions, err := h.revisions.find(c.Request.Context(), params.Namespace, nil, h.kind.gvk.Kind, params.ResourceName)
+	if err != nil {
+		api.HandleError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, api.ListResult{Items: revisions, TotalItems: len(revisions)})
+}
+
+// RestoreRouteRevision restores the spec of route to the revision, the
+// deleted route is created again.
+func (h *RouteHandler) RestoreRouteRevision(c *gin.Context) {
+	params := handleRequestParams(c, resourceNameRoute)
+	ctx := c.Request.Context()
+	dryRun, err := isDryRun(c)
+	if err != nil {
+		api.HandleBadRequest(c, err)
+		return
+	}
+	revisions, err := h.revisions.find(ctx, params.Namespace, nil, h.kind.gvk.Kind, params.ResourceName)
+	if err != nil {
+		api.HandleError(c, err)
+		return
+	}
+	revision, err := revisionOf(c, revisions)
+	if err != nil {
+		api.HandleError(c, err)
+		return
+	}
+
+	route := h.kind.newObject()
+	err = h.client.Get(ctx, types.NamespacedName{Namespace: params.Namespace, Name: params.ResourceName}, route)
+	exists := err == nil
+	if err != nil && !errors.IsNotFound(err) {
+		api.HandleError(c, err)
+		return
+	}
+	if exists {
+		err = h.kind.checkNoActiveCanary(route)
+	} else {
+		route.SetNamespace(params.Namespace)
+		route.SetName(params.ResourceName)
+		route.SetLabels(revision.Labels)
+		route.SetAnnotations(revision.Annotations)
+	}
+	if err == nil {
+		err = setSpec(route, revision.Spec)
+	}
+	var warnings []string
+	if err == nil {
+		warnings, err = h.validateParentRefs(ctx, route)
+	}
+	addWarnings(c, warnings)
+	if err != nil {
+		api.HandleError(c, err)
+		return
+	}
+
+	operation := OperationUpdate
+	if exists {
+		var opts []rtclient.UpdateOption
+		if dryRun {
+			opts = append(opts, rtclient.DryRunAll)
+		}
+		err = h.client.Update(ctx, route, opts...)
+	} else {
+		operation = OperationCreate
+		var opts []rtclient.CreateOption
+		if dryRun {
+			opts = append(opts, rtclient.DryRunAll)
+		}
+		err = h.client.Create(ctx, route, opts...)
+	}
+	if err != nil {
+		if errors.IsConflict(err) {
+			api.HandleConflict(c, err)
+			return
+		}
+		api.HandleError(c, err)
+		return
+	}
+	if !dryRun {
+		h.revisions.record(ctx, route, h.kind.gvk.Kind, operation)
+	}
+	c.JSON(http.StatusOK, route)
+}
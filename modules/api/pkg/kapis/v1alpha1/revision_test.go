@@ -0,0 +1,153 @@
+package v1alpha1
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"testing"
+
+	"github.com/kubesphere-extensions/gateway-api/pkg/utils/gatewayutil"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	rtclient "sigs.k8s.io/controller-runtime/pkg/client"
+	apisv1 "sigs.k8s.io/gateway-api/apis/v1"
+)
+
+func TestRevisionHistoryAdd(t *testing.T) {
+	gateway := testGateway("demo", "gw", map[string]string{
+		gatewayutil.LabelScope:            gatewayutil.ScopeNamespace,
+		gatewayutil.LabelWorkingNamespace: "demo",
+	})
+	tests := []struct {
+		name     string
+		existing *corev1.ConfigMap
+		wantErr  bool
+		// wantRevisions is the number of revisions after the add
+		wantRevisions int
+	}{
+		{
+			name:          "first revision",
+			wantRevisions: 1,
+		},
+		{
+			name:          "revisions of gateway",
+			existing:      newRevisionsConfigMap(gateway, kindGateway),
+			wantRevisions: 1,
+		},
+		{
+			name: "ConfigMap of the same name",
+			existing: &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{
+				Namespace: "demo",
+				Name:      revisionsName(kindGateway, "gw"),
+			}},
+			wantErr: true,
+		},
+		{
+			name: "revisions of another object",
+			existing: &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{
+				Namespace:   "demo",
+				Name:        revisionsName(kindGateway, "gw"),
+				Labels:      map[string]string{LabelRevisionKind: kindGateway},
+				Annotations: map[string]string{AnnotationRevisionOf: "other"},
+			}},
+			wantErr: true,
+		},
+		{
+			name: "revisions in another scope",
+			existing: newRevisionsConfigMap(testGateway("demo", "gw", map[string]string{
+				gatewayutil.LabelScope: gatewayutil.ScopeCluster,
+			}), kindGateway),
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var objects []rtclient.Object
+			if tt.existing != nil {
+				objects = append(objects, tt.existing)
+			}
+			_, client := newTestServer(t, objects...)
+			history := &revisionHistory{client: client}
+
+			err := history.add(context.Background(), gateway, kindGateway, OperationCreate)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("add() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			configMap := &corev1.ConfigMap{}
+			err = client.Get(context.Background(), types.NamespacedName{Namespace: "demo", Name: revisionsName(kindGateway, "gw")}, configMap)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(configMap.Data) != tt.wantRevisions {
+				t.Errorf("revisions = %v, want %d", configMap.Data, tt.wantRevisions)
+			}
+		})
+	}
+}
+
+func TestRestoreRouteRevision(t *testing.T) {
+	route := &apisv1.HTTPRoute{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "demo", Name: "web"},
+		Spec:       apisv1.HTTPRouteSpec{Hostnames: []apisv1.Hostname{"new.example.com"}},
+	}
+	restored := route.DeepCopy()
+	restored.Spec.Hostnames = []apisv1.Hostname{"old.example.com"}
+	configMap := newRevisionsConfigMap(route, httpRouteKind.gvk.Kind)
+	configMap.Data = map[string]string{}
+	for i, object := range []*apisv1.HTTPRoute{restored, route} {
+		spec, err := specOf(object)
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err := json.Marshal(Revision{Revision: i + 1, Operation: OperationUpdate, Spec: spec})
+		if err != nil {
+			t.Fatal(err)
+		}
+		configMap.Data[strconv.Itoa(i+1)] = string(data)
+	}
+
+	tests := []struct {
+		name          string
+		query         string
+		wantHostname  apisv1.Hostname
+		wantRevisions int
+	}{
+		{
+			name:          "restore",
+			wantHostname:  "old.example.com",
+			wantRevisions: 3,
+		},
+		{
+			name:          "dry run",
+			query:         "?dryRun=All",
+			wantHostname:  "new.example.com",
+			wantRevisions: 2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			engine, client := newTestServer(t, route.DeepCopy(), configMap.DeepCopy())
+			recorder := serve(t, engine, http.MethodPost, "/namespaces/demo/httproutes/web/revisions/1/restore"+tt.query, nil)
+			if recorder.Code != http.StatusOK {
+				t.Fatalf("code = %d: %s", recorder.Code, recorder.Body.String())
+			}
+
+			got := &apisv1.HTTPRoute{}
+			if err := client.Get(context.Background(), types.NamespacedName{Namespace: "demo", Name: "web"}, got); err != nil {
+				t.Fatal(err)
+			}
+			if len(got.Spec.Hostnames) != 1 || got.Spec.Hostnames[0] != tt.wantHostname {
+				t.Errorf("hostnames = %v, want %s", got.Spec.Hostnames, tt.wantHostname)
+			}
+			history := &corev1.ConfigMap{}
+			if err := client.Get(context.Background(), rtclient.ObjectKeyFromObject(configMap), history); err != nil {
+				t.Fatal(err)
+			}
+			if len(history.Data) != tt.wantRevisions {
+				t.Errorf("revisions = %d, want %d", len(history.Data), tt.wantRevisions)
+			}
+		})
+	}
+}
@@ -0,0 +1,494 @@
+package v1alpha1
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kubesphere-extensions/gateway-api/pkg/api"
+	"github.com/kubesphere-extensions/gateway-api/pkg/apiserver/query"
+	"github.com/kubesphere-extensions/gateway-api/pkg/apiserver/watch"
+	"github.com/kubesphere-extensions/gateway-api/pkg/utils/gatewayutil"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	rtclient "sigs.k8s.io/controller-runtime/pkg/client"
+	apisv1 "sigs.k8s.io/gateway-api/apis/v1"
+	"sigs.k8s.io/gateway-api/apis/v1alpha2"
+)
+
+const (
+	resourceNameRoute = "route"
+)
+
+// routeKind describes a kind of Gateway API route, so that all kinds of routes
+// are served by the same handler.
+type routeKind struct {
+	// plural resource name of the route, e.g. httproutes
+	resource string
+	gvk      schema.GroupVersionKind
+
+	// protocols are the listener protocols which can serve the route
+	protocols []apisv1.ProtocolType
+
+	newObject func() rtclient.Object
+	newList   func() rtclient.ObjectList
+	// spec returns the common spec of route, which holds the parentRefs
+	spec func(rtclient.Object) *apisv1.CommonRouteSpec
+	// backendRefs returns the backendRefs of all rules of route
+	backendRefs func(rtclient.Object) []apisv1.BackendObjectReference
+}
+
+var httpRouteKind = routeKind{
+	resource:    "httproutes",
+	gvk:         apisv1.SchemeGroupVersion.WithKind("HTTPRoute"),
+	protocols:   []apisv1.ProtocolType{apisv1.HTTPProtocolType, apisv1.HTTPSProtocolType},
+	newObject:   func() rtclient.Object { return &apisv1.HTTPRoute{} },
+	newList:     func() rtclient.ObjectList { return &apisv1.HTTPRouteList{} },
+	spec:        func(o rtclient.Object) *apisv1.CommonRouteSpec { return &o.(*apisv1.HTTPRoute).Spec.CommonRouteSpec },
+	backendRefs: httpRouteBackendRefs,
+}
+
+var grpcRouteKind = routeKind{
+	resource:    "grpcroutes",
+	gvk:         apisv1.SchemeGroupVersion.WithKind("GRPCRoute"),
+	protocols:   []apisv1.ProtocolType{apisv1.HTTPProtocolType, apisv1.HTTPSProtocolType},
+	newObject:   func() rtclient.Object { return &apisv1.GRPCRoute{} },
+	newList:     func() rtclient.ObjectList { return &apisv1.GRPCRouteList{} },
+	spec:        func(o rtclient.Object) *apisv1.CommonRouteSpec { return &o.(*apisv1.GRPCRoute).Spec.CommonRouteSpec },
+	backendRefs: grpcRouteBackendRefs,
+}
+
+var tlsRouteKind = routeKind{
+	resource:    "tlsroutes",
+	gvk:         v1alpha2.SchemeGroupVersion.WithKind("TLSRoute"),
+	protocols:   []apisv1.ProtocolType{apisv1.TLSProtocolType},
+	newObject:   func() rtclient.Object { return &v1alpha2.TLSRoute{} },
+	newList:     func() rtclient.ObjectList { return &v1alpha2.TLSRouteList{} },
+	spec:        func(o rtclient.Object) *apisv1.CommonRouteSpec { return &o.(*v1alpha2.TLSRoute).Spec.CommonRouteSpec },
+	backendRefs: tlsRouteBackendRefs,
+}
+
+var tcpRouteKind = routeKind{
+	resource:    "tcproutes",
+	gvk:         v1alpha2.SchemeGroupVersion.WithKind("TCPRoute"),
+	protocols:   []apisv1.ProtocolType{apisv1.TCPProtocolType},
+	newObject:   func() rtclient.Object { return &v1alpha2.TCPRoute{} },
+	newList:     func() rtclient.ObjectList { return &v1alpha2.TCPRouteList{} },
+	spec:        func(o rtclient.Object) *apisv1.CommonRouteSpec { return &o.(*v1alpha2.TCPRoute).Spec.CommonRouteSpec },
+	backendRefs: tcpRouteBackendRefs,
+}
+
+var udpRouteKind = routeKind{
+	resource:    "udproutes",
+	gvk:         v1alpha2.SchemeGroupVersion.WithKind("UDPRoute"),
+	protocols:   []apisv1.ProtocolType{apisv1.UDPProtocolType},
+	newObject:   func() rtclient.Object { return &v1alpha2.UDPRoute{} },
+	newList:     func() rtclient.ObjectList { return &v1alpha2.UDPRouteList{} },
+	spec:        func(o rtclient.Object) *apisv1.CommonRouteSpec { return &o.(*v1alpha2.UDPRoute).Spec.CommonRouteSpec },
+	backendRefs: udpRouteBackendRefs,
+}
+
+func httpRouteBackendRefs(o rtclient.Object) []apisv1.BackendObjectReference {
+	refs := make([]apisv1.BackendObjectReference, 0)
+	for _, rule := range o.(*apisv1.HTTPRoute).Spec.Rules {
+		for _, ref := range rule.BackendRefs {
+			refs = append(refs, ref.BackendObjectReference)
+		}
+	}
+	return refs
+}
+
+func grpcRouteBackendRefs(o rtclient.Object) []apisv1.BackendObjectReference {
+	refs := make([]apisv1.BackendObjectReference, 0)
+	for _, rule := range o.(*apisv1.GRPCRoute).Spec.Rules {
+		for _, ref := range rule.BackendRefs {
+			refs = append(refs, ref.BackendObjectReference)
+		}
+	}
+	return refs
+}
+
+func tlsRouteBackendRefs(o rtclient.Object) []apisv1.BackendObjectReference {
+	refs := make([]apisv1.BackendObjectReference, 0)
+	for _, rule := range o.(*v1alpha2.TLSRoute).Spec.Rules {
+		refs = append(refs, backendObjectReferences(rule.BackendRefs)...)
+	}
+	return refs
+}
+
+func tcpRouteBackendRefs(o rtclient.Object) []apisv1.BackendObjectReference {
+	refs := make([]apisv1.BackendObjectReference, 0)
+	for _, rule := range o.(*v1alpha2.TCPRoute).Spec.Rules {
+		refs = append(refs, backendObjectReferences(rule.BackendRefs)...)
+	}
+	return refs
+}
+
+func udpRouteBackendRefs(o rtclient.Object) []apisv1.BackendObjectReference {
+	refs := make([]apisv1.BackendObjectReference, 0)
+	for _, rule := range o.(*v1alpha2.UDPRoute).Spec.Rules {
+		refs = append(refs, backendObjectReferences(rule.BackendRefs)...)
+	}
+	return refs
+}
+
+func backendObjectReferences(backendRefs []apisv1.BackendRef) []apisv1.BackendObjectReference {
+	refs := make([]apisv1.BackendObjectReference, 0, len(backendRefs))
+	for _, ref := range backendRefs {
+		refs = append(refs, ref.BackendObjectReference)
+	}
+	return refs
+}
+
+// routeKinds are the kinds of routes served by the API, each kind is selected
+// by its resource name in the request path.
+var routeKinds = []routeKind{httpRouteKind, grpcRouteKind, tlsRouteKind, tcpRouteKind, udpRouteKind}
+
+type RouteHandler struct {
+	client    rtclient.Client
+	kind      routeKind
+	revisions *revisionHistory
+	// broadcaster serves the watches of routes, nil if watch is not supported
+	broadcaster *watch.Broadcaster
+}
+
+// NewRouteHandler returns the handler of kind, the revisions of routes are
+// written by serviceClient.
+func NewRouteHandler(client, serviceClient rtclient.Client, kind routeKind, informers cache.Informers) *RouteHandler {
+	h := &RouteHandler{client: client, kind: kind, revisions: &revisionHistory{client: serviceClient}}
+	if informers != nil {
+		h.broadcaster = watch.NewBroadcaster(informers, kind.newObject)
+	}
+	return h
+}
+
+func (h *RouteHandler) GetRoute(c *gin.Context) {
+	params := handleRequestParams(c, resourceNameRoute)
+	route := h.kind.newObject()
+	err := h.client.Get(c.Request.Context(), types.NamespacedName{Namespace: params.Namespace, Name: params.ResourceName}, route)
+	if err != nil {
+		api.HandleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, route)
+}
+
+func (h *RouteHandler) ListRoutes(c *gin.Context) {
+	params := handleRequestParams(c, resourceNameRoute)
+	q := query.ParseQueryParameter(c)
+	if watching, _ := strconv.ParseBool(c.Query(paramWatch)); watching {
+		h.watchRoutes(c, params, q)
+		return
+	}
+	routes, err := h.listRoutes(c.Request.Context(), params, q)
+	if err != nil {
+		api.HandleError(c, err)
+		return
+	}
+
+	start, end := q.Pagination.GetValidPagination(len(routes))
+	c.JSON(http.StatusOK, api.ListResult{Items: routes[start:end], TotalItems: len(routes)})
+}
+
+// listRoutes lists the routes of the namespace, or of all namespaces in the
+// workspace, which match the label selector and filters of query.
+func (h *RouteHandler) listRoutes(ctx context.Context, params ResourceParams, q *query.Query) ([]rtclient.Object, error) {
+	selector, err := labels.Parse(q.LabelSelector)
+	if err != nil {
+		return nil, errors.NewBadRequest(err.Error())
+	}
+
+	var namespaces map[string]bool
+	if params.Namespace == "" && params.Workspace != "" {
+		namespaces, err = h.workspaceNamespaces(ctx, params.Workspace)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	list := h.kind.newList()
+	err = h.client.List(ctx, list, rtclient.MatchingLabelsSelector{Selector: selector}, rtclient.InNamespace(params.Namespace))
+	if err != nil {
+		return nil, err
+	}
+	objects, err := meta.ExtractList(list)
+	if err != nil {
+		return nil, err
+	}
+
+	routes := make([]rtclient.Object, 0, len(objects))
+	for _, object := range objects {
+		route := object.(rtclient.Object)
+		if namespaces != nil && !namespaces[route.GetNamespace()] {
+			continue
+		}
+		if name := q.Filters[query.ParameterName]; name != "" && !strings.Contains(route.GetName(), name) {
+			continue
+		}
+		routes = append(routes, route)
+	}
+
+	sort.SliceStable(routes, func(i, j int) bool {
+		return q.Compare(routes[i], routes[j])
+	})
+	return routes, nil
+}
+
+// workspaceNamespaces returns the names of namespaces which belong to workspace.
+func (h *RouteHandler) workspaceNamespaces(ctx context.Context, workspace string) (map[string]bool, error) {
+	list := &corev1.NamespaceList{}
+	err := h.client.List(ctx, list, rtclient.MatchingLabels{gatewayutil.LabelWorkspace: workspace})
+	if err != nil {
+		return nil, err
+	}
+	namespaces := make(map[string]bool, len(list.Items))
+	for _, item := range list.Items {
+		namespaces[item.Name] = true
+	}
+	return namespaces, nil
+}
+
+func (h *RouteHandler) CreateRoute(c *gin.Context) {
+	params := handleRequestParams(c, resourceNameRoute)
+	route := h.kind.newObject()
+	err := c.ShouldBind(route)
+	if err != nil {
+		api.HandleBadRequest(c, err)
+		return
+	}
+	if route.GetNamespace() == "" {
+		route.SetNamespace(params.Namespace)
+	}
+	if route.GetNamespace() != params.Namespace {
+		api.HandleBadRequest(c, field.Invalid(field.NewPath("metadata", "namespace"), route.GetNamespace(), fmt.Sprintf("must be %s", params.Namespace)))
+		return
+	}
+
+	warnings, err := h.validateParentRefs(c.Request.Context(), route)
+	addWarnings(c, warnings)
+	if err != nil {
+		api.HandleError(c, err)
+		return
+	}
+
+	err = h.client.Create(c.Request.Context(), route)
+	if err != nil {
+		api.HandleError(c, err)
+		return
+	}
+	h.revisions.record(c.Request.Context(), route, h.kind.gvk.Kind, OperationCreate)
+	c.JSON(http.StatusOK, route)
+}
+
+func (h *RouteHandler) UpdateRoute(c *gin.Context) {
+	params := handleRequestParams(c, resourceNameRoute)
+	route := h.kind.newObject()
+	err := c.ShouldBind(route)
+	if err != nil {
+		api.HandleBadRequest(c, err)
+		return
+	}
+	if route.GetName() != params.ResourceName {
+		api.HandleBadRequest(c, field.Invalid(field.NewPath("metadata", "name"), route.GetName(), fmt.Sprintf("must be %s", params.ResourceName)))
+		return
+	}
+	if route.GetNamespace() == "" {
+		route.SetNamespace(params.Namespace)
+	}
+	if route.GetNamespace() != params.Namespace {
+		api.HandleBadRequest(c, field.Invalid(field.NewPath("metadata", "namespace"), route.GetNamespace(), fmt.Sprintf("must be %s", params.Namespace)))
+		return
+	}
+
+	existing := h.kind.newObject()
+	err = h.client.Get(c.Request.Context(), types.NamespacedName{Namespace: params.Namespace, Name: params.ResourceName}, existing)
+	if err != nil {
+		api.HandleError(c, err)
+		return
+	}
+	// an update without resourceVersion would overwrite changes it has not seen
+	if route.GetResourceVersion() == "" {
+		api.HandleBadRequest(c, field.Required(field.NewPath("metadata", "resourceVersion"), fmt.Sprintf("required to update the %s", h.kind.gvk.Kind)))
+		return
+	}
+	// the rules of an active canary release are rendered by its steps, which
+	// would drop the changes of them
+	if !canaryUnchanged(existing, route) {
+		err = h.kind.checkNoActiveCanary(existing)
+		if err != nil {
+			api.HandleError(c, err)
+			return
+		}
+	}
+
+	warnings, err := h.validateParentRefs(c.Request.Context(), route)
+	addWarnings(c, warnings)
+	if err != nil {
+		api.HandleError(c, err)
+		return
+	}
+
+	err = h.client.Update(c.Request.Context(), route)
+	if err != nil {
+		if errors.IsConflict(err) {
+			api.HandleConflict(c, err)
+			return
+		}
+		api.HandleError(c, err)
+		return
+	}
+	h.revisions.record(c.Request.Context(), route, h.kind.gvk.Kind, OperationUpdate)
+	c.JSON(http.StatusOK, route)
+}
+
+func (h *RouteHandler) DeleteRoute(c *gin.Context) {
+	params := handleRequestParams(c, resourceNameRoute)
+	route := h.kind.newObject()
+	err := h.client.Get(c.Request.Context(), types.NamespacedName{Namespace: params.Namespace, Name: params.ResourceName}, route)
+	if err != nil {
+		api.HandleError(c, err)
+		return
+	}
+	err = h.client.Delete(c.Request.Context(), route)
+	if err != nil {
+		api.HandleError(c, err)
+		return
+	}
+	h.revisions.record(c.Request.Context(), route, h.kind.gvk.Kind, OperationDelete)
+	c.JSON(http.StatusOK, gin.H{"status": "success"})
+}
+
+// validateParentRefs checks that every Gateway referenced by the route exists
+// and allows routes of the route namespace to attach by its scope labels. The
+// gateways which are not managed by KubeSphere are warned about, since their
+// attachment is not checked.
+func (h *RouteHandler) validateParentRefs(ctx context.Context, route rtclient.Object) ([]string, error) {
+	var warnings []string
+	for _, ref := range h.kind.spec(route).ParentRefs {
+		if !isGatewayRef(ref) {
+			continue
+		}
+		namespace := route.GetNamespace()
+		if ref.Namespace != nil {
+			namespace = string(*ref.Namespace)
+		}
+
+		gateway := &apisv1.Gateway{}
+		err := h.client.Get(ctx, types.NamespacedName{Namespace: namespace, Name: string(ref.Name)}, gateway)
+		if err != nil {
+			if errors.IsNotFound(err) {
+				return nil, errors.NewBadRequest(fmt.Sprintf("parent gateway %s/%s not found", namespace, ref.Name))
+			}
+			return nil, err
+		}
+
+		if _, managed := gateway.Labels[gatewayutil.LabelScope]; !managed {
+			warnings = append(warnings, fmt.Sprintf("gateway %s/%s is not managed by KubeSphere, the routes of namespace %s are attached by its AllowedRoutes only",
+				gateway.Namespace, gateway.Name, route.GetNamespace()))
+		}
+		allowed, err := h.canAttach(ctx, gateway, route.GetNamespace())
+		if err != nil {
+			return nil, err
+		}
+		if !allowed {
+			return nil, errors.NewBadRequest(fmt.Sprintf("routes of namespace %s are not allowed to attach to gateway %s/%s",
+				route.GetNamespace(), gateway.Namespace, gateway.Name))
+		}
+		if !h.kind.servedBy(gateway, ref) {
+			return nil, errors.NewBadRequest(fmt.Sprintf("no listener of gateway %s/%s can serve %s %s",
+				gateway.Namespace, gateway.Name, h.kind.gvk.Kind, route.GetName()))
+		}
+	}
+	return warnings, nil
+}
+
+// canAttach reports whether the routes of namespace may attach to the gateway
+// according to its scope labels.
+func (h *RouteHandler) canAttach(ctx context.Context, gateway *apisv1.Gateway, namespace string) (bool, error) {
+	switch gateway.Labels[gatewayutil.LabelScope] {
+	case gatewayutil.ScopeCluster:
+		return true, nil
+	case gatewayutil.ScopeNamespace:
+		return gateway.Labels[gatewayutil.LabelWorkingNamespace] == namespace, nil
+	case gatewayutil.ScopeWorkspace:
+		ns := &corev1.Namespace{}
+		err := h.client.Get(ctx, types.NamespacedName{Name: namespace}, ns)
+		if err != nil {
+			return false, err
+		}
+		workspace := gateway.Labels[gatewayutil.LabelWorkingWorkspace]
+		return workspace != "" && ns.Labels[gatewayutil.LabelWorkspace] == workspace, nil
+	default:
+		// gateways which are not managed by KubeSphere are left to their own AllowedRoutes
+		return true, nil
+	}
+}
+
+// isGatewayRef reports whether the parentRef refers to a Gateway.
+func isGatewayRef(ref apisv1.ParentReference) bool {
+	if ref.Group != nil && string(*ref.Group) != apisv1.GroupName {
+		return false
+	}
+	return ref.Kind == nil || string(*ref.Kind) == kindGateway
+}
+
+// servedBy reports whether any listener of gateway selected by the parentRef
+// has a protocol and AllowedRoutes kinds which can serve the route kind.
+func (k routeKind) servedBy(gateway *apisv1.Gateway, ref apisv1.ParentReference) bool {
+	for _, listener := range gateway.Spec.Listeners {
+		if !selectsListener(ref, listener) {
+			continue
+		}
+		if k.servesProtocol(listener.Protocol) && k.allowedBy(listener.AllowedRoutes) {
+			return true
+		}
+	}
+	return false
+}
+
+// selectsListener reports whether the parentRef selects the listener by its
+// sectionName and port, a parentRef without both selects all listeners.
+func selectsListener(ref apisv1.ParentReference, listener apisv1.Listener) bool {
+	if ref.SectionName != nil && *ref.SectionName != listener.Name {
+		return false
+	}
+	return ref.Port == nil || *ref.Port == listener.Port
+}
+
+func (k routeKind) servesProtocol(protocol apisv1.ProtocolType) bool {
+	for _, p := range k.protocols {
+		if p == protocol {
+			return true
+		}
+	}
+	return false
+}
+
+// allowedBy reports whether the route kind is allowed by the kinds of
+// AllowedRoutes, which allows the kinds matching listener protocol if empty.
+func (k routeKind) allowedBy(allowedRoutes *apisv1.AllowedRoutes) bool {
+	if allowedRoutes == nil || len(allowedRoutes.Kinds) == 0 {
+		return true
+	}
+	for _, kind := range allowedRoutes.Kinds {
+		group := apisv1.GroupName
+		if kind.Group != nil {
+			group = string(*kind.Group)
+		}
+		if group == k.gvk.Group && string(kind.Kind) == k.gvk.Kind {
+			return true
+		}
+	}
+	return false
+}
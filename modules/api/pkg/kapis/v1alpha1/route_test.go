@@ -0,0 +1,113 @@
+package v1alpha1
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/kubesphere-extensions/gateway-api/pkg/utils/gatewayutil"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	apisv1 "sigs.k8s.io/gateway-api/apis/v1"
+)
+
+func TestUpdateRoute(t *testing.T) {
+	tests := []struct {
+		name            string
+		resourceVersion string
+		// gatewayLabels are the labels of the parent gateway
+		gatewayLabels map[string]string
+		wantCode      int
+		wantField     string
+		wantWarning   bool
+	}{
+		{
+			name:          "missing resourceVersion",
+			gatewayLabels: map[string]string{gatewayutil.LabelScope: gatewayutil.ScopeCluster},
+			wantCode:      http.StatusUnprocessableEntity,
+			wantField:     "metadata.resourceVersion",
+		},
+		{
+			name:            "stale resourceVersion",
+			resourceVersion: "1",
+			gatewayLabels:   map[string]string{gatewayutil.LabelScope: gatewayutil.ScopeCluster},
+			wantCode:        http.StatusConflict,
+		},
+		{
+			name:            "managed gateway",
+			resourceVersion: "999",
+			gatewayLabels:   map[string]string{gatewayutil.LabelScope: gatewayutil.ScopeCluster},
+			wantCode:        http.StatusOK,
+		},
+		{
+			name:            "gateway of another namespace",
+			resourceVersion: "999",
+			gatewayLabels: map[string]string{
+				gatewayutil.LabelScope:            gatewayutil.ScopeNamespace,
+				gatewayutil.LabelWorkingNamespace: "other",
+			},
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name:            "unmanaged gateway is warned",
+			resourceVersion: "999",
+			wantCode:        http.StatusOK,
+			wantWarning:     true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gatewayNamespace := apisv1.Namespace(defaultWorkingNamespace)
+			route := &apisv1.HTTPRoute{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "demo", Name: "web"},
+				Spec: apisv1.HTTPRouteSpec{CommonRouteSpec: apisv1.CommonRouteSpec{
+					ParentRefs: []apisv1.ParentReference{{Namespace: &gatewayNamespace, Name: "gw"}},
+				}},
+			}
+			engine, _ := newTestServer(t, route.DeepCopy(), testGateway(defaultWorkingNamespace, "gw", tt.gatewayLabels))
+
+			route.ResourceVersion = tt.resourceVersion
+			route.Spec.Hostnames = []apisv1.Hostname{"example.com"}
+			recorder := serve(t, engine, http.MethodPut, "/namespaces/demo/httproutes/web", route)
+			if recorder.Code != tt.wantCode {
+				t.Fatalf("code = %d, want %d: %s", recorder.Code, tt.wantCode, recorder.Body.String())
+			}
+			if tt.wantField != "" {
+				status := statusOf(t, recorder)
+				if status.Details == nil || len(status.Details.Causes) == 0 || status.Details.Causes[0].Field != tt.wantField {
+					t.Errorf("causes = %+v, want field %s", status.Details, tt.wantField)
+				}
+			}
+			warned := false
+			for _, warning := range recorder.Header().Values("Warning") {
+				warned = warned || strings.Contains(warning, "not managed")
+			}
+			if warned != tt.wantWarning {
+				t.Errorf("warnings = %v, want warning %v", recorder.Header().Values("Warning"), tt.wantWarning)
+			}
+		})
+	}
+}
+
+func TestServedBy(t *testing.T) {
+	tests := []struct {
+		name     string
+		kind     routeKind
+		protocol apisv1.ProtocolType
+		want     bool
+	}{
+		{name: "HTTPRoute on HTTPS", kind: httpRouteKind, protocol: apisv1.HTTPSProtocolType, want: true},
+		{name: "TLSRoute on TLS", kind: tlsRouteKind, protocol: apisv1.TLSProtocolType, want: true},
+		{name: "TCPRoute on TCP", kind: tcpRouteKind, protocol: apisv1.TCPProtocolType, want: true},
+		{name: "TCPRoute on TLS", kind: tcpRouteKind, protocol: apisv1.TLSProtocolType},
+		{name: "UDPRoute on TCP", kind: udpRouteKind, protocol: apisv1.TCPProtocolType},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gateway := testGateway("demo", "gw", nil)
+			gateway.Spec.Listeners = []apisv1.Listener{{Name: "listener", Protocol: tt.protocol, Port: 443}}
+			if got := tt.kind.servedBy(gateway, apisv1.ParentReference{Name: "gw"}); got != tt.want {
+				t.Errorf("servedBy() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
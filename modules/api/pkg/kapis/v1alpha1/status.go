@@ -0,0 +1,334 @@
+package v1alpha1
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kubesphere-extensions/gateway-api/pkg/api"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/selection"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/sets"
+	rtclient "sigs.k8s.io/controller-runtime/pkg/client"
+	apisv1 "sigs.k8s.io/gateway-api/apis/v1"
+)
+
+const (
+	// paramWithStatus asks the gateway list to include the status summary of gateways
+	paramWithStatus = "withStatus"
+
+	HealthHealthy   = "Healthy"
+	HealthDegraded  = "Degraded"
+	HealthUnhealthy = "Unhealthy"
+	HealthPending   = "Pending"
+
+	kindDeployment = "Deployment"
+)
+
+// GatewayStatusSummary is the health of gateway computed from its conditions,
+// the statuses of listeners and its data plane workloads.
+type GatewayStatusSummary struct {
+	// Health is one of Healthy, Degraded, Unhealthy and Pending
+	Health string `json:"health"`
+	// Reasons explain why the gateway is not healthy
+	Reasons []string `json:"reasons,omitempty"`
+
+	Accepted   *metav1.Condition             `json:"accepted,omitempty"`
+	Programmed *metav1.Condition             `json:"programmed,omitempty"`
+	Addresses  []apisv1.GatewayStatusAddress `json:"addresses,omitempty"`
+	Listeners  []ListenerStatusSummary       `json:"listeners"`
+	Workloads  []WorkloadStatus              `json:"workloads"`
+}
+
+type ListenerStatusSummary struct {
+	Name           apisv1.SectionName `json:"name"`
+	AttachedRoutes int32              `json:"attachedRoutes"`
+	Programmed     bool               `json:"programmed"`
+	Conflicted     bool               `json:"conflicted"`
+	// ResolvedRefsProblems are the messages of the ResolvedRefs condition
+	// which is not true
+	ResolvedRefsProblems []string `json:"resolvedRefsProblems,omitempty"`
+}
+
+// WorkloadStatus is the readiness of a Service or Deployment of data plane.
+type WorkloadStatus struct {
+	Kind          string `json:"kind"`
+	Namespace     string `json:"namespace"`
+	Name          string `json:"name"`
+	Ready         bool   `json:"ready"`
+	Replicas      int32  `json:"replicas,omitempty"`
+	ReadyReplicas int32  `json:"readyReplicas,omitempty"`
+	Message       string `json:"message,omitempty"`
+}
+
+// Gateway is a gateway with its status summary.
+type Gateway struct {
+	apisv1.Gateway `json:",inline"`
+	Summary        *GatewayStatusSummary `json:"summary,omitempty"`
+}
+
+func (h *Handler) GetGatewayStatus(c *gin.Context) {
+	params := handleRequestParams(c, resourceNameGateway)
+	gateway, err := h.getGateway(c.Request.Context(), params)
+	if err != nil {
+		api.HandleError(c, err)
+		return
+	}
+
+	workloads, err := h.newWorkloadIndex(c.Request.Context(), gateway)
+	if err != nil {
+		api.HandleError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gatewayStatusSummary(gateway, workloads))
+}
+
+func gatewayStatusSummary(gateway *apisv1.Gateway, index *workloadIndex) *GatewayStatusSummary {
+	summary := &GatewayStatusSummary{
+		Health:     HealthHealthy,
+		Accepted:   meta.FindStatusCondition(gateway.Status.Conditions, string(apisv1.GatewayConditionAccepted)),
+		Programmed: meta.FindStatusCondition(gateway.Status.Conditions, string(apisv1.GatewayConditionProgrammed)),
+		Addresses:  gateway.Status.Addresses,
+		Listeners:  make([]ListenerStatusSummary, 0, len(gateway.Status.Listeners)),
+		Workloads:  make([]WorkloadStatus, 0),
+	}
+	worsen := func(health, reason string) {
+		if healthRank[health] > healthRank[summary.Health] {
+			summary.Health = health
+		}
+		summary.Reasons = append(summary.Reasons, reason)
+	}
+
+	switch {
+	case summary.Accepted == nil || summary.Programmed == nil:
+		worsen(HealthPending, "the gateway has not been reconciled by its controller")
+	case summary.Accepted.ObservedGeneration < gateway.Generation || summary.Programmed.ObservedGeneration < gateway.Generation:
+		worsen(HealthPending, "the latest generation of gateway has not been reconciled by its controller")
+	}
+	if summary.Accepted != nil && summary.Accepted.Status != metav1.ConditionTrue {
+		worsen(HealthUnhealthy, fmt.Sprintf("not accepted: %s", summary.Accepted.Message))
+	}
+	if summary.Programmed != nil && summary.Programmed.Status != metav1.ConditionTrue {
+		if summary.Programmed.Reason == string(apisv1.GatewayReasonPending) {
+			worsen(HealthPending, fmt.Sprintf("not programmed: %s", summary.Programmed.Message))
+		} else {
+			worsen(HealthUnhealthy, fmt.Sprintf("not programmed: %s", summary.Programmed.Message))
+		}
+	}
+	if summary.Programmed != nil && summary.Programmed.Status == metav1.ConditionTrue && len(gateway.Status.Addresses) == 0 {
+		worsen(HealthDegraded, "no address is assigned")
+	}
+
+	for _, status := range gateway.Status.Listeners {
+		listener := ListenerStatusSummary{
+			Name:           status.Name,
+			AttachedRoutes: status.AttachedRoutes,
+			Programmed:     meta.IsStatusConditionTrue(status.Conditions, string(apisv1.ListenerConditionProgrammed)),
+			Conflicted:     meta.IsStatusConditionTrue(status.Conditions, string(apisv1.ListenerConditionConflicted)),
+		}
+		if resolved := meta.FindStatusCondition(status.Conditions, string(apisv1.ListenerConditionResolvedRefs)); resolved != nil && resolved.Status != metav1.ConditionTrue {
+			listener.ResolvedRefsProblems = append(listener.ResolvedRefsProblems, fmt.Sprintf("%s: %s", resolved.Reason, resolved.Message))
+			worsen(HealthDegraded, fmt.Sprintf("references of listener %s are not resolved", status.Name))
+		}
+		if listener.Conflicted {
+			worsen(HealthDegraded, fmt.Sprintf("listener %s is conflicted", status.Name))
+		} else if !listener.Programmed {
+			worsen(HealthDegraded, fmt.Sprintf("listener %s is not programmed", status.Name))
+		}
+		summary.Listeners = append(summary.Listeners, listener)
+	}
+
+	workloads := index.workloadsOf(gateway)
+	for _, workload := range workloads {
+		switch {
+		case workload.Ready:
+		case workload.Kind == kindDeployment && workload.ReadyReplicas > 0:
+			worsen(HealthDegraded, fmt.Sprintf("%s %s is partially ready", workload.Kind, workload.Name))
+		default:
+			worsen(HealthUnhealthy, fmt.Sprintf("%s %s is not ready", workload.Kind, workload.Name))
+		}
+	}
+	summary.Workloads = workloads
+	return summary
+}
+
+var healthRank = map[string]int{HealthHealthy: 0, HealthPending: 1, HealthDegraded: 2, HealthUnhealthy: 3}
+
+// dataPlaneLabels are the labels by which the implementations link the data
+// plane workloads to their gateways. The workloads are in the namespace of
+// gateway if the namespace label is empty.
+var dataPlaneLabels = []struct{ name, namespace string }{
+	// Envoy Gateway runs the data plane in the namespace of its controller
+	{name: "gateway.envoyproxy.io/owning-gateway-name", namespace: "gateway.envoyproxy.io/owning-gateway-namespace"},
+	// Istio and the other implementations following GEP-1762
+	{name: "gateway.networking.k8s.io/gateway-name"},
+}
+
+// workloadIndex is the data plane workloads of gateways, which are listed once
+// for all gateways of a request.
+type workloadIndex struct {
+	byOwner   map[types.UID][]WorkloadStatus
+	byGateway map[types.NamespacedName][]WorkloadStatus
+}
+
+// newWorkloadIndex lists the Services and Deployments which are labeled with
+// the gateways by the data plane labels. Only the labeled workloads are listed,
+// the ones found through owner references are among them.
+func (h *Handler) newWorkloadIndex(ctx context.Context, gateways ...*apisv1.Gateway) (*workloadIndex, error) {
+	index := &workloadIndex{byOwner: map[types.UID][]WorkloadStatus{}, byGateway: map[types.NamespacedName][]WorkloadStatus{}}
+	names := map[string]sets.Set[string]{}
+	for _, gateway := range gateways {
+		if names[gateway.Namespace] == nil {
+			names[gateway.Namespace] = sets.New[string]()
+		}
+		names[gateway.Namespace].Insert(gateway.Name)
+	}
+	if len(names) == 0 {
+		return index, nil
+	}
+
+	listOptions := make([][]rtclient.ListOption, 0)
+	for _, label := range dataPlaneLabels {
+		if label.namespace != "" {
+			allNames := sets.New[string]()
+			for _, set := range names {
+				allNames = allNames.Union(set)
+			}
+			selector, err := inSelector(map[string][]string{label.name: sets.List(allNames), label.namespace: sets.List(sets.KeySet(names))})
+			if err != nil {
+				return nil, err
+			}
+			listOptions = append(listOptions, []rtclient.ListOption{rtclient.MatchingLabelsSelector{Selector: selector}})
+			continue
+		}
+		for _, namespace := range sets.List(sets.KeySet(names)) {
+			selector, err := inSelector(map[string][]string{label.name: sets.List(names[namespace])})
+			if err != nil {
+				return nil, err
+			}
+			listOptions = append(listOptions, []rtclient.ListOption{rtclient.InNamespace(namespace), rtclient.MatchingLabelsSelector{Selector: selector}})
+		}
+	}
+
+	// a workload may be listed more than once
+	seen := sets.New[types.UID]()
+	for _, opts := range listOptions {
+		services := &corev1.ServiceList{}
+		err := h.client.List(ctx, services, opts...)
+		if err != nil {
+			return nil, err
+		}
+		for i := range services.Items {
+			if !seen.Has(services.Items[i].UID) {
+				seen.Insert(services.Items[i].UID)
+				index.add(&services.Items[i], serviceStatus(&services.Items[i]))
+			}
+		}
+	}
+	for _, opts := range listOptions {
+		deployments := &appsv1.DeploymentList{}
+		err := h.client.List(ctx, deployments, opts...)
+		if err != nil {
+			return nil, err
+		}
+		for i := range deployments.Items {
+			if !seen.Has(deployments.Items[i].UID) {
+				seen.Insert(deployments.Items[i].UID)
+				index.add(&deployments.Items[i], deploymentStatus(&deployments.Items[i]))
+			}
+		}
+	}
+	return index, nil
+}
+
+// inSelector selects the objects whose labels are in the values.
+func inSelector(values map[string][]string) (labels.Selector, error) {
+	selector := labels.NewSelector()
+	for key, value := range values {
+		requirement, err := labels.NewRequirement(key, selection.In, value)
+		if err != nil {
+			return nil, err
+		}
+		selector = selector.Add(*requirement)
+	}
+	return selector, nil
+}
+
+func (index *workloadIndex) add(object metav1.Object, workload WorkloadStatus) {
+	for _, ref := range object.GetOwnerReferences() {
+		index.byOwner[ref.UID] = append(index.byOwner[ref.UID], workload)
+	}
+	for _, label := range dataPlaneLabels {
+		name, ok := object.GetLabels()[label.name]
+		if !ok {
+			continue
+		}
+		key := types.NamespacedName{Namespace: object.GetNamespace(), Name: name}
+		if label.namespace != "" {
+			key.Namespace = object.GetLabels()[label.namespace]
+		}
+		index.byGateway[key] = append(index.byGateway[key], workload)
+		break
+	}
+}
+
+// workloadsOf returns the workloads which are owned by the gateway or labeled
+// with it.
+func (index *workloadIndex) workloadsOf(gateway *apisv1.Gateway) []WorkloadStatus {
+	workloads := make([]WorkloadStatus, 0)
+	seen := sets.New[string]()
+	for _, workload := range append(index.byOwner[gateway.UID], index.byGateway[rtclient.ObjectKeyFromObject(gateway)]...) {
+		key := workload.Kind + "/" + workload.Namespace + "/" + workload.Name
+		if !seen.Has(key) {
+			seen.Insert(key)
+			workloads = append(workloads, workload)
+		}
+	}
+	return workloads
+}
+
+func serviceStatus(service *corev1.Service) WorkloadStatus {
+	workload := WorkloadStatus{Kind: kindService, Namespace: service.Namespace, Name: service.Name, Ready: true}
+	if service.Spec.Type == corev1.ServiceTypeLoadBalancer && len(service.Status.LoadBalancer.Ingress) == 0 {
+		workload.Ready = false
+		workload.Message = "the load balancer is not provisioned"
+	}
+	return workload
+}
+
+func deploymentStatus(deployment *appsv1.Deployment) WorkloadStatus {
+	replicas := int32(1)
+	if deployment.Spec.Replicas != nil {
+		replicas = *deployment.Spec.Replicas
+	}
+	workload := WorkloadStatus{
+		Kind:          kindDeployment,
+		Namespace:     deployment.Namespace,
+		Name:          deployment.Name,
+		Replicas:      replicas,
+		ReadyReplicas: deployment.Status.ReadyReplicas,
+		Ready:         deployment.Status.ReadyReplicas >= replicas && deployment.Status.ObservedGeneration >= deployment.Generation,
+	}
+	for _, condition := range deployment.Status.Conditions {
+		if condition.Type == appsv1.DeploymentAvailable && condition.Status != corev1.ConditionTrue {
+			workload.Message = condition.Message
+		}
+	}
+	return workload
+}
+
+// ownedBy reports whether the object has an owner reference to the gateway.
+func ownedBy(object metav1.Object, gateway *apisv1.Gateway) bool {
+	for _, ref := range object.GetOwnerReferences() {
+		if ref.UID == gateway.UID {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,67 @@
+package v1alpha1
+
+import (
+	"context"
+	"sort"
+	"testing"
+
+	"github.com/kubesphere-extensions/gateway-api/pkg/scheme"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	rtclient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+)
+
+func TestNewWorkloadIndex(t *testing.T) {
+	gateway := testGateway("demo", "gw", nil)
+	gateway.UID = "gateway-uid"
+	objects := []rtclient.Object{
+		// Istio
+		&corev1.Service{ObjectMeta: metav1.ObjectMeta{Namespace: "demo", Name: "gw-istio", UID: "gw-istio",
+			Labels: map[string]string{"gateway.networking.k8s.io/gateway-name": "gw"}}},
+		// Envoy Gateway
+		&appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Namespace: "envoy-gateway-system", Name: "envoy-demo-gw", UID: "envoy-demo-gw",
+			Labels: map[string]string{
+				"gateway.envoyproxy.io/owning-gateway-name":      "gw",
+				"gateway.envoyproxy.io/owning-gateway-namespace": "demo",
+			}}},
+		// the gateway of the same name in another namespace
+		&corev1.Service{ObjectMeta: metav1.ObjectMeta{Namespace: "envoy-gateway-system", Name: "envoy-other-gw", UID: "envoy-other-gw",
+			Labels: map[string]string{
+				"gateway.envoyproxy.io/owning-gateway-name":      "gw",
+				"gateway.envoyproxy.io/owning-gateway-namespace": "other",
+			}}},
+		// owned by the gateway without the data plane labels
+		&corev1.Service{ObjectMeta: metav1.ObjectMeta{Namespace: "demo", Name: "unlabeled", UID: "unlabeled",
+			OwnerReferences: []metav1.OwnerReference{{APIVersion: "gateway.networking.k8s.io/v1", Kind: "Gateway", Name: "gw", UID: gateway.UID}}}},
+		&corev1.Service{ObjectMeta: metav1.ObjectMeta{Namespace: "demo", Name: "unrelated", UID: "unrelated"}},
+	}
+
+	client := fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(objects...).WithInterceptorFuncs(interceptor.Funcs{
+		List: func(ctx context.Context, client rtclient.WithWatch, list rtclient.ObjectList, opts ...rtclient.ListOption) error {
+			listOptions := &rtclient.ListOptions{}
+			listOptions.ApplyOptions(opts)
+			if listOptions.LabelSelector == nil || listOptions.LabelSelector.Empty() {
+				t.Errorf("%T is listed without label selector", list)
+			}
+			return client.List(ctx, list, opts...)
+		},
+	}).Build()
+	h := &Handler{client: client}
+
+	index, err := h.newWorkloadIndex(context.Background(), gateway)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, workload := range index.workloadsOf(gateway) {
+		got = append(got, workload.Kind+"/"+workload.Namespace+"/"+workload.Name)
+	}
+	sort.Strings(got)
+	want := []string{"Deployment/envoy-gateway-system/envoy-demo-gw", "Service/demo/gw-istio"}
+	if len(got) != len(want) || got[0] != want[0] || got[1] != want[1] {
+		t.Errorf("workloads = %v, want %v", got, want)
+	}
+}
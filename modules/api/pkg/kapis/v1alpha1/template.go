@@ -0,0 +1,152 @@
+package v1alpha1
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/kubesphere-extensions/gateway-api/pkg/api"
+	"github.com/kubesphere-extensions/gateway-api/pkg/utils/gatewayutil"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	apisv1 "sigs.k8s.io/gateway-api/apis/v1"
+)
+
+// GatewayTemplate is the request of creating a gateway from the listener
+// templates of GatewayClass.
+type GatewayTemplate struct {
+	Name             string `json:"name"`
+	Namespace        string `json:"namespace,omitempty"`
+	GatewayClassName string `json:"gatewayClassName"`
+	// Listeners are the names of listener templates to use, all templates are
+	// used if it is empty
+	Listeners []string `json:"listeners,omitempty"`
+	// Hostnames are the hostnames of the listeners routed by hostname
+	Hostnames []string `json:"hostnames,omitempty"`
+	// TLSSecret is the certificate of the listeners which terminate TLS, it is
+	// in the namespace of gateway if the namespace is not set
+	TLSSecret *SecretReference `json:"tlsSecret,omitempty"`
+
+	Labels      map[string]string `json:"labels,omitempty"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+type SecretReference struct {
+	Namespace string `json:"namespace,omitempty"`
+	Name      string `json:"name"`
+}
+
+// CreateGatewayFromTemplate expands the listener templates of GatewayClass
+// into a gateway, which is created the same way as CreateGateway.
+func (h *Handler) CreateGatewayFromTemplate(c *gin.Context) {
+	params := handleRequestParams(c, resourceNameGateway)
+	template := &GatewayTemplate{}
+	err := c.ShouldBind(template)
+	if err != nil {
+		api.HandleBadRequest(c, err)
+		return
+	}
+
+	var errs field.ErrorList
+	if template.Name == "" {
+		errs = append(errs, field.Required(field.NewPath("name"), ""))
+	}
+	if template.GatewayClassName == "" {
+		errs = append(errs, field.Required(field.NewPath("gatewayClassName"), ""))
+	}
+	if template.TLSSecret != nil && template.TLSSecret.Name == "" {
+		errs = append(errs, field.Required(field.NewPath("tlsSecret", "name"), ""))
+	}
+	if len(errs) != 0 {
+		api.HandleBadRequest(c, errs.ToAggregate())
+		return
+	}
+
+	gatewayClass := &apisv1.GatewayClass{}
+	err = h.client.Get(c.Request.Context(), types.NamespacedName{Name: template.GatewayClassName}, gatewayClass)
+	if err != nil {
+		api.HandleError(c, err)
+		return
+	}
+	templates, err := gatewayutil.ParseListeners(gatewayClass)
+	if err != nil {
+		api.HandleError(c, err)
+		return
+	}
+	templates, errs = selectTemplates(templates, template.Listeners)
+	if len(errs) != 0 {
+		api.HandleBadRequest(c, errs.ToAggregate())
+		return
+	}
+
+	gateway := &apisv1.Gateway{}
+	gateway.Name = template.Name
+	gateway.Namespace = template.Namespace
+	gateway.Labels = template.Labels
+	gateway.Annotations = template.Annotations
+	gateway.Spec.GatewayClassName = apisv1.ObjectName(template.GatewayClassName)
+	errs = setScope(params, gateway)
+	if len(errs) != 0 {
+		api.HandleBadRequest(c, errs.ToAggregate())
+		return
+	}
+
+	var certificate *apisv1.SecretObjectReference
+	if template.TLSSecret != nil {
+		certificate = &apisv1.SecretObjectReference{Name: apisv1.ObjectName(template.TLSSecret.Name)}
+		if template.TLSSecret.Namespace != "" && template.TLSSecret.Namespace != gateway.Namespace {
+			namespace := apisv1.Namespace(template.TLSSecret.Namespace)
+			certificate.Namespace = &namespace
+		}
+	}
+	gateway.Spec.Listeners, errs = gatewayutil.ExpandListeners(templates, template.Hostnames, certificate)
+	if len(errs) != 0 {
+		api.HandleError(c, errors.NewInvalid(apisv1.SchemeGroupVersion.WithKind(kindGateway).GroupKind(), gateway.Name, errs))
+		return
+	}
+
+	// the default AllowedRoutes of templates may not allow routes from the
+	// namespaces out of the scope of gateway.
+	for i := range gateway.Spec.Listeners {
+		listener := &gateway.Spec.Listeners[i]
+		if listener.AllowedRoutes == nil {
+			continue
+		}
+		routes, err := gatewayutil.NewAllowedRoutes(gateway)
+		if err != nil {
+			api.HandleBadRequest(c, err)
+			return
+		}
+		listener.AllowedRoutes.Namespaces = routes.Namespaces
+	}
+
+	h.createGateway(c, params, gateway)
+}
+
+// selectTemplates returns the templates of the names, or all templates if no
+// name is given.
+func selectTemplates(templates []gatewayutil.Listener, names []string) ([]gatewayutil.Listener, field.ErrorList) {
+	if len(names) == 0 {
+		return templates, nil
+	}
+	var errs field.ErrorList
+	available := sets.New[string]()
+	for _, template := range templates {
+		available.Insert(template.Name)
+	}
+	wanted := sets.New[string]()
+	for i, name := range names {
+		if !available.Has(name) {
+			errs = append(errs, field.NotFound(field.NewPath("listeners").Index(i), name))
+			continue
+		}
+		wanted.Insert(name)
+	}
+
+	selected := make([]gatewayutil.Listener, 0, len(wanted))
+	for _, template := range templates {
+		if wanted.Has(template.Name) {
+			selected = append(selected, template)
+		}
+	}
+	return selected, errs
+}
@@ -0,0 +1,100 @@
+package v1alpha1
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kubesphere-extensions/gateway-api/pkg/api"
+	"github.com/kubesphere-extensions/gateway-api/pkg/utils/gatewayutil"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	apisv1 "sigs.k8s.io/gateway-api/apis/v1"
+	"sigs.k8s.io/yaml"
+)
+
+// GatewayValidation is the report of validating a gateway, the conflicts with
+// the listeners of other gateways of the class are also reported in causes.
+type GatewayValidation struct {
+	Valid     bool                           `json:"valid"`
+	Warnings  []string                       `json:"warnings,omitempty"`
+	Causes    []metav1.StatusCause           `json:"causes,omitempty"`
+	Conflicts []gatewayutil.ListenerConflict `json:"conflicts"`
+}
+
+// ValidateGateway validates the gateway without writing it. The gateway in the
+// request body is validated as an update of the existing gateway, and the
+// existing gateway is validated if the body is empty.
+func (h *Handler) ValidateGateway(c *gin.Context) {
+	params := handleRequestParams(c, resourceNameGateway)
+	body, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		api.HandleBadRequest(c, err)
+		return
+	}
+
+	existing, err := h.getGateway(c.Request.Context(), params)
+	if err != nil {
+		api.HandleError(c, err)
+		return
+	}
+
+	gateway := existing
+	if len(bytes.TrimSpace(body)) != 0 {
+		gateway = &apisv1.Gateway{}
+		err = yaml.Unmarshal(body, gateway)
+		if err != nil {
+			api.HandleBadRequest(c, err)
+			return
+		}
+		if gateway.Name == "" {
+			gateway.Name = existing.Name
+		}
+		if gateway.Name != existing.Name {
+			api.HandleBadRequest(c, field.Invalid(field.NewPath("metadata", "name"), gateway.Name, fmt.Sprintf("must be %s", existing.Name)))
+			return
+		}
+		if gateway.Namespace == "" {
+			gateway.Namespace = existing.Namespace
+		}
+		if gateway.Namespace != existing.Namespace {
+			api.HandleBadRequest(c, field.Forbidden(field.NewPath("metadata", "namespace"), "the namespace of gateway can not be changed"))
+			return
+		}
+		err = keepScopeLabels(existing, gateway)
+		if err != nil {
+			api.HandleBadRequest(c, err)
+			return
+		}
+		err = gatewayutil.DefaultAllowedRoutes(gateway)
+		if err != nil {
+			api.HandleBadRequest(c, err)
+			return
+		}
+	}
+
+	validation, err := gatewayutil.ValidateGateway(c.Request.Context(), h.client, gateway, existing)
+	if err != nil {
+		api.HandleError(c, err)
+		return
+	}
+
+	result := GatewayValidation{
+		Valid:     len(validation.Errors) == 0,
+		Warnings:  validation.Warnings,
+		Conflicts: validation.Conflicts,
+	}
+	if result.Conflicts == nil {
+		result.Conflicts = make([]gatewayutil.ListenerConflict, 0)
+	}
+	for _, fieldErr := range validation.Errors {
+		result.Causes = append(result.Causes, metav1.StatusCause{
+			Type:    metav1.CauseType(fieldErr.Type),
+			Message: fieldErr.ErrorBody(),
+			Field:   fieldErr.Field,
+		})
+	}
+	c.JSON(http.StatusOK, result)
+}
@@ -0,0 +1,92 @@
+package v1alpha1
+
+import (
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kubesphere-extensions/gateway-api/pkg/api"
+	"github.com/kubesphere-extensions/gateway-api/pkg/apiserver/query"
+	"github.com/kubesphere-extensions/gateway-api/pkg/apiserver/watch"
+	"github.com/kubesphere-extensions/gateway-api/pkg/utils/gatewayutil"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	rtclient "sigs.k8s.io/controller-runtime/pkg/client"
+	apisv1 "sigs.k8s.io/gateway-api/apis/v1"
+)
+
+// watchGateways streams the events of the gateways which the list of the same
+// request would return.
+func (h *Handler) watchGateways(c *gin.Context, params ResourceParams, q *query.Query) {
+	if h.gateways == nil {
+		api.HandleError(c, errors.NewMethodNotSupported(apisv1.Resource(resourceGateways), "watch"))
+		return
+	}
+	selector, err := gatewaySelector(params, q)
+	if err != nil {
+		api.HandleError(c, err)
+		return
+	}
+	visible, err := h.gatewayVisibility(c.Request.Context(), params)
+	if err != nil {
+		api.HandleError(c, err)
+		return
+	}
+
+	w, err := h.gateways.Watch(c.Request.Context(), watch.ResourceVersion(c), func(object rtclient.Object) (bool, error) {
+		gateway := object.(*apisv1.Gateway)
+		if !selector.Matches(labels.Set(gateway.Labels)) || !matchesGatewayFilters(gateway, q) {
+			return false, nil
+		}
+		return visible(gateway)
+	})
+	if err != nil {
+		api.HandleError(c, err)
+		return
+	}
+	watch.ServeSSE(c, w, apisv1.SchemeGroupVersion.WithKind(kindGateway), watch.DefaultBookmarkInterval)
+}
+
+// watchRoutes streams the events of the routes which the list of the same
+// request would return. The workspace of a route is checked when its event is
+// sent, so no events are sent when a namespace joins or leaves the workspace,
+// its routes are sent by their next changes.
+func (h *RouteHandler) watchRoutes(c *gin.Context, params ResourceParams, q *query.Query) {
+	if h.broadcaster == nil {
+		api.HandleError(c, errors.NewMethodNotSupported(h.kind.gvk.GroupVersion().WithResource(h.kind.resource).GroupResource(), "watch"))
+		return
+	}
+	selector, err := labels.Parse(q.LabelSelector)
+	if err != nil {
+		api.HandleBadRequest(c, err)
+		return
+	}
+
+	ctx := c.Request.Context()
+	w, err := h.broadcaster.Watch(ctx, watch.ResourceVersion(c), func(route rtclient.Object) (bool, error) {
+		if params.Namespace != "" && route.GetNamespace() != params.Namespace {
+			return false, nil
+		}
+		if !selector.Matches(labels.Set(route.GetLabels())) {
+			return false, nil
+		}
+		if name := q.Filters[query.ParameterName]; name != "" && !strings.Contains(route.GetName(), name) {
+			return false, nil
+		}
+		if params.Namespace == "" && params.Workspace != "" {
+			namespace := &corev1.Namespace{}
+			err := h.client.Get(ctx, types.NamespacedName{Name: route.GetNamespace()}, namespace)
+			if err != nil {
+				return false, rtclient.IgnoreNotFound(err)
+			}
+			return namespace.Labels[gatewayutil.LabelWorkspace] == params.Workspace, nil
+		}
+		return true, nil
+	})
+	if err != nil {
+		api.HandleError(c, err)
+		return
+	}
+	watch.ServeSSE(c, w, h.kind.gvk, watch.DefaultBookmarkInterval)
+}
@@ -6,6 +6,8 @@ import (
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	apisv1 "sigs.k8s.io/gateway-api/apis/v1"
+	"sigs.k8s.io/gateway-api/apis/v1alpha2"
+	"sigs.k8s.io/gateway-api/apis/v1beta1"
 )
 
 // Scheme contains all types of custom Scheme and kubernetes client-go Scheme.
@@ -18,4 +20,6 @@ func init() {
 	_ = clientgoscheme.AddToScheme(Scheme)
 
 	utilruntime.Must(apisv1.Install(Scheme))
+	utilruntime.Must(v1alpha2.Install(Scheme))
+	utilruntime.Must(v1beta1.Install(Scheme))
 }
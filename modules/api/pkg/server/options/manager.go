@@ -0,0 +1,88 @@
+package options
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/pflag"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+type ManagerOptions struct {
+	// metrics bind address, "0" disables the metrics endpoint
+	MetricsBindAddress string
+
+	// webhook port number, 0 disables the webhook server. The webhook server
+	// needs the certificate in WebhookCertDir, so it is disabled by default
+	WebhookPort int
+
+	// directory which contains the tls.crt and tls.key of webhook server
+	WebhookCertDir string
+
+	// enable leader election for the controllers of manager, so that only one
+	// replica advances the canary releases. It is enabled by default when the
+	// namespace of lease is known, which is the case in the cluster. The APIs
+	// are served by all replicas
+	LeaderElect bool
+
+	// namespace of the leader election lease, defaults to the namespace of pod
+	// from the POD_NAMESPACE environment variable or the service account
+	LeaderElectionNamespace string
+}
+
+const (
+	envPodNamespace = "POD_NAMESPACE"
+	// serviceAccountNamespaceFile holds the namespace of the pod in the cluster
+	serviceAccountNamespaceFile = "/var/run/secrets/kubernetes.io/serviceaccount/namespace"
+)
+
+func NewManagerOptions() *ManagerOptions {
+	namespace := podNamespace()
+	// create default controller-runtime manager options
+	m := ManagerOptions{
+		MetricsBindAddress:      ":8080",
+		WebhookPort:             0,
+		WebhookCertDir:          "",
+		LeaderElect:             namespace != "",
+		LeaderElectionNamespace: namespace,
+	}
+
+	return &m
+}
+
+// podNamespace returns the namespace which the apiserver runs in, it is empty
+// out of the cluster.
+func podNamespace() string {
+	if namespace := os.Getenv(envPodNamespace); namespace != "" {
+		return namespace
+	}
+	data, err := os.ReadFile(serviceAccountNamespaceFile)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
+
+func (m *ManagerOptions) Validate() []error {
+	var errs []error
+
+	if m.WebhookPort != 0 {
+		if msg := validation.IsValidPortNum(m.WebhookPort); len(msg) != 0 {
+			errs = append(errs, fmt.Errorf("invalid webhook port, %v", msg))
+		}
+	}
+	if m.LeaderElect && m.LeaderElectionNamespace == "" {
+		errs = append(errs, fmt.Errorf("leader-election-namespace is required by leader-elect out of the cluster"))
+	}
+
+	return errs
+}
+
+func (m *ManagerOptions) AddFlags(fs *pflag.FlagSet, c *ManagerOptions) {
+	fs.StringVar(&m.MetricsBindAddress, "metrics-bind-address", c.MetricsBindAddress, "metrics bind address, set to 0 to disable the metrics endpoint")
+	fs.IntVar(&m.WebhookPort, "webhook-port", c.WebhookPort, "webhook port number, set to 0 to disable the admission webhooks, which need the certificate in webhook-cert-dir")
+	fs.StringVar(&m.WebhookCertDir, "webhook-cert-dir", c.WebhookCertDir, "directory which contains the tls.crt and tls.key of webhook server")
+	fs.BoolVar(&m.LeaderElect, "leader-elect", c.LeaderElect, "whether to enable leader election for the controllers or not, the canary controller requires it with more than one replica. Enabled by default in the cluster, and disabled out of it")
+	fs.StringVar(&m.LeaderElectionNamespace, "leader-election-namespace", c.LeaderElectionNamespace, "namespace of the leader election lease, defaults to the namespace of pod from the POD_NAMESPACE environment variable or the service account")
+}
@@ -24,6 +24,20 @@ type ServerRunOptions struct {
 
 	// tls private key file
 	TlsPrivateKey string
+
+	// authenticate the caller and authorize requests by SubjectAccessReviews
+	EnableAuthorization bool
+
+	// the identity which the writes are done as, one of serviceaccount, impersonate and token
+	ClientMode string
+
+	// CA file which verifies the client certificates of the front proxies
+	// forwarding the identity of caller by the X-Remote-* headers, the headers
+	// are ignored if it is empty
+	RequestHeaderClientCAFile string
+
+	// common names of the client certificates of front proxies, any name is allowed if empty
+	RequestHeaderAllowedNames []string
 }
 
 func NewServerRunOptions() *ServerRunOptions {
@@ -34,6 +48,9 @@ func NewServerRunOptions() *ServerRunOptions {
 		SecurePort:    0,
 		TlsCertFile:   "",
 		TlsPrivateKey: "",
+
+		EnableAuthorization: true,
+		ClientMode:          "serviceaccount",
 	}
 
 	return &s
@@ -66,6 +83,15 @@ func (s *ServerRunOptions) Validate() []error {
 		errs = append(errs, fmt.Errorf("invalid secure port, %v", msg))
 	}
 
+	if s.RequestHeaderClientCAFile != "" {
+		if s.SecurePort == 0 {
+			errs = append(errs, fmt.Errorf("requestheader client CA file requires secure serving"))
+		}
+		if _, err := os.Stat(s.RequestHeaderClientCAFile); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
 	return errs
 }
 
@@ -75,4 +101,12 @@ func (s *ServerRunOptions) AddFlags(fs *pflag.FlagSet, c *ServerRunOptions) {
 	fs.IntVar(&s.SecurePort, "secure-port", s.SecurePort, "secure port number")
 	fs.StringVar(&s.TlsCertFile, "tls-cert-file", c.TlsCertFile, "tls cert file")
 	fs.StringVar(&s.TlsPrivateKey, "tls-private-key", c.TlsPrivateKey, "tls private key")
+	fs.BoolVar(&s.EnableAuthorization, "enable-authorization", c.EnableAuthorization, "authenticate the caller and authorize requests by SubjectAccessReviews")
+	fs.StringVar(&s.ClientMode, "client-mode", c.ClientMode, "the identity which the writes to Kubernetes are done as, "+
+		"serviceaccount for the service account of server, impersonate for impersonating the caller and token for passing the bearer token of caller through, "+
+		"impersonate and token require enable-authorization")
+	fs.StringVar(&s.RequestHeaderClientCAFile, "requestheader-client-ca-file", c.RequestHeaderClientCAFile, "CA file which verifies the client certificates of front proxies, "+
+		"the identity headers X-Remote-User, X-Remote-Group and X-Remote-Extra- are only accepted from the front proxies by mTLS")
+	fs.StringSliceVar(&s.RequestHeaderAllowedNames, "requestheader-allowed-names", c.RequestHeaderAllowedNames, "common names of the client certificates of front proxies, "+
+		"any name verified by requestheader-client-ca-file is allowed if empty")
 }
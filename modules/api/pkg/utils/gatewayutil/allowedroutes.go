@@ -0,0 +1,56 @@
+package gatewayutil
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	apisv1 "sigs.k8s.io/gateway-api/apis/v1"
+)
+
+// DefaultAllowedRoutes sets the AllowedRoutes of listeners which have none
+// according to the scope of gateway.
+func DefaultAllowedRoutes(gateway *apisv1.Gateway) error {
+	for i := range gateway.Spec.Listeners {
+		if gateway.Spec.Listeners[i].AllowedRoutes != nil {
+			continue
+		}
+		routes, err := NewAllowedRoutes(gateway)
+		if err != nil {
+			return err
+		}
+		gateway.Spec.Listeners[i].AllowedRoutes = routes
+	}
+	return nil
+}
+
+// NewAllowedRoutes returns the AllowedRoutes of gateway scope, which allows
+// routes from the working namespace of a namespace scoped gateway, from the
+// namespaces of the working workspace of a workspace scoped gateway and from
+// all namespaces of a cluster scoped gateway.
+func NewAllowedRoutes(gateway *apisv1.Gateway) (*apisv1.AllowedRoutes, error) {
+	err := ValidateScopeLabels(gateway)
+	if err != nil {
+		return nil, err
+	}
+
+	var namespaces *apisv1.RouteNamespaces
+	switch gateway.Labels[LabelScope] {
+	case ScopeNamespace:
+		namespaces = newRouteNamespacesBySelector(corev1.LabelMetadataName, gateway.Labels[LabelWorkingNamespace])
+	case ScopeWorkspace:
+		namespaces = newRouteNamespacesBySelector(LabelWorkspace, gateway.Labels[LabelWorkingWorkspace])
+	default:
+		from := apisv1.NamespacesFromAll
+		namespaces = &apisv1.RouteNamespaces{From: &from}
+	}
+	return &apisv1.AllowedRoutes{Namespaces: namespaces}, nil
+}
+
+func newRouteNamespacesBySelector(key, value string) *apisv1.RouteNamespaces {
+	from := apisv1.NamespacesFromSelector
+	return &apisv1.RouteNamespaces{
+		From: &from,
+		Selector: &metav1.LabelSelector{
+			MatchLabels: map[string]string{key: value},
+		},
+	}
+}
@@ -0,0 +1,387 @@
+package gatewayutil
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	apisv1 "sigs.k8s.io/gateway-api/apis/v1"
+)
+
+const (
+	// AnnotationCanary holds the canary release of an HTTPRoute in JSON, which
+	// is advanced by the canary controller.
+	AnnotationCanary = "gatewayapi.kubesphere.io/canary"
+
+	// CanaryProgressing means the steps of canary are being advanced.
+	CanaryProgressing = "Progressing"
+	// CanaryHolding means the canary holds the weight until it is changed,
+	// promoted or aborted.
+	CanaryHolding = "Holding"
+	// CanarySucceeded means the canary has replaced the stable backend.
+	CanarySucceeded = "Succeeded"
+
+	// weightScale scales the weights of backendRefs, so that the percentage
+	// of canary keeps the proportions of other backends of the rule. The
+	// scaled weights are reduced to fit the maximum weight.
+	weightScale = 100
+	// maxBackendWeight is the maximum weight of a backendRef
+	maxBackendWeight = 1000000
+
+	// maxRouteRules and maxRuleMatches are the limits of HTTPRoute, which the
+	// rules pinning requests to canary count against
+	maxRouteRules  = 16
+	maxRuleMatches = 64
+
+	headerCookie = "Cookie"
+)
+
+// CanaryBackend is a Service in the namespace of route.
+type CanaryBackend struct {
+	Name apisv1.ObjectName `json:"name"`
+	// Port of Service, the port of stable backendRef is used if not set
+	Port *apisv1.PortNumber `json:"port,omitempty"`
+}
+
+// CanaryCookie pins the requests carrying the cookie to canary.
+type CanaryCookie struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// CanaryStep shifts Weight percent of traffic to canary, and the next step
+// begins after Pause.
+type CanaryStep struct {
+	Weight int32           `json:"weight"`
+	Pause  metav1.Duration `json:"pause,omitempty"`
+}
+
+type CanarySpec struct {
+	Stable CanaryBackend `json:"stable"`
+	Canary CanaryBackend `json:"canary"`
+	// Weight is the percentage of traffic to canary, it is set by the steps
+	// if there are any
+	Weight int32 `json:"weight,omitempty"`
+	// Headers pin the requests which match all of them to canary
+	Headers []apisv1.HTTPHeaderMatch `json:"headers,omitempty"`
+	// Cookie pins the requests which carry it to canary, it is matched by a
+	// regular expression on the Cookie header
+	Cookie *CanaryCookie `json:"cookie,omitempty"`
+	Steps  []CanaryStep  `json:"steps,omitempty"`
+}
+
+type CanaryStatus struct {
+	// Phase is one of Progressing, Holding and Succeeded
+	Phase string `json:"phase"`
+	// Step is the index of the current step
+	Step int `json:"step"`
+	// Weight is the percentage of traffic to canary currently
+	Weight             int32       `json:"weight"`
+	LastTransitionTime metav1.Time `json:"lastTransitionTime"`
+}
+
+// Canary is a canary release of an HTTPRoute. The rules of route are rendered
+// from the rules before the canary starts, which are restored when it is
+// aborted.
+type Canary struct {
+	CanarySpec `json:",inline"`
+	Status     CanaryStatus `json:"status"`
+	// Baseline is the rules of route before the canary starts
+	Baseline []apisv1.HTTPRouteRule `json:"baseline,omitempty"`
+}
+
+// CanaryOf returns the canary release of route, nil if there is none.
+func CanaryOf(route *apisv1.HTTPRoute) (*Canary, error) {
+	value, ok := route.Annotations[AnnotationCanary]
+	if !ok {
+		return nil, nil
+	}
+	canary := &Canary{}
+	if err := json.Unmarshal([]byte(value), canary); err != nil {
+		return nil, fmt.Errorf("invalid annotation %s of HTTPRoute %s/%s: %v", AnnotationCanary, route.Namespace, route.Name, err)
+	}
+	return canary, nil
+}
+
+// SetCanary renders the rules of route by the canary, and stores the canary
+// in the annotation of route. The route is left unchanged if the rendered
+// rules exceed the limits of HTTPRoute.
+func SetCanary(route *apisv1.HTTPRoute, canary *Canary) error {
+	data, err := json.Marshal(canary)
+	if err != nil {
+		return err
+	}
+	rules := canary.render(route.Namespace)
+	if errs := validateRules(rules); len(errs) != 0 {
+		return errs.ToAggregate()
+	}
+	if route.Annotations == nil {
+		route.Annotations = map[string]string{}
+	}
+	route.Annotations[AnnotationCanary] = string(data)
+	route.Spec.Rules = rules
+	return nil
+}
+
+// Active reports whether the canary is still shifting traffic.
+func (c *Canary) Active() bool {
+	return c.Status.Phase == CanaryProgressing || c.Status.Phase == CanaryHolding
+}
+
+// Start starts the canary from the first step, or holds the weight of spec if
+// there are no steps. The canary which shifts all traffic succeeds at once.
+func (c *Canary) Start(now time.Time) {
+	c.Status = CanaryStatus{Phase: CanaryHolding, Weight: c.Weight, LastTransitionTime: metav1.NewTime(now)}
+	if len(c.Steps) != 0 {
+		c.Status.Phase = CanaryProgressing
+		c.Status.Weight = c.Steps[0].Weight
+	} else if c.Weight == weightScale {
+		c.Status.Phase = CanarySucceeded
+	}
+}
+
+// Advance moves the canary to the next step once the pause of current step
+// has passed, and returns whether the canary is changed and the time to wait
+// for the next step. The canary succeeds after the pause of the last step
+// shifting all traffic, and holds after the pause of another last step.
+func (c *Canary) Advance(now time.Time) (bool, time.Duration) {
+	if c.Status.Phase != CanaryProgressing || c.Status.Step >= len(c.Steps) {
+		return false, 0
+	}
+	remaining := c.Status.LastTransitionTime.Add(c.Steps[c.Status.Step].Pause.Duration).Sub(now)
+	if remaining > 0 {
+		return false, remaining
+	}
+
+	c.Status.LastTransitionTime = metav1.NewTime(now)
+	if c.Status.Step == len(c.Steps)-1 {
+		c.Status.Phase = CanaryHolding
+		if c.Status.Weight == weightScale {
+			c.Status.Phase = CanarySucceeded
+		}
+		return true, 0
+	}
+	c.Status.Step++
+	c.Status.Weight = c.Steps[c.Status.Step].Weight
+	return true, c.Steps[c.Status.Step].Pause.Duration
+}
+
+// render returns the rules of route at the current weight of canary. The
+// stable backendRefs of baseline are split between stable and canary, and the
+// pinned requests are routed by the rules which only have the canary backend.
+// The stable backendRefs are replaced by canary once it succeeds.
+func (c *Canary) render(namespace string) []apisv1.HTTPRouteRule {
+	rules := make([]apisv1.HTTPRouteRule, 0, len(c.Baseline))
+	for _, baseline := range c.Baseline {
+		rule := baseline.DeepCopy()
+		index := c.stableIndex(namespace, rule)
+		if index < 0 {
+			rules = append(rules, *rule)
+			continue
+		}
+		canaryRef := c.canaryRef(rule.BackendRefs[index])
+		if c.Status.Phase == CanarySucceeded {
+			rule.BackendRefs[index] = canaryRef
+			rules = append(rules, *rule)
+			continue
+		}
+
+		if pinned := c.pinnedMatches(rule.Matches); len(pinned) != 0 {
+			pinnedRule := rule.DeepCopy()
+			pinnedRule.Matches = pinned
+			pinnedRule.BackendRefs = []apisv1.HTTPBackendRef{canaryRef}
+			rules = append(rules, *pinnedRule)
+		}
+
+		rule.BackendRefs = append(rule.BackendRefs, canaryRef)
+		weights := make([]int64, len(rule.BackendRefs))
+		for i := range rule.BackendRefs[:len(rule.BackendRefs)-1] {
+			weights[i] = int64(backendWeight(rule.BackendRefs[i])) * weightScale
+		}
+		stable := int64(backendWeight(rule.BackendRefs[index]))
+		weights[index] = stable * int64(weightScale-c.Status.Weight)
+		weights[len(weights)-1] = stable * int64(c.Status.Weight)
+		for i, weight := range reduceWeights(weights) {
+			rule.BackendRefs[i].Weight = &weight
+		}
+		rules = append(rules, *rule)
+	}
+	return rules
+}
+
+// stableIndex returns the index of the stable backendRef of rule, -1 if the
+// rule does not route to stable.
+func (c *Canary) stableIndex(namespace string, rule *apisv1.HTTPRouteRule) int {
+	for i, ref := range rule.BackendRefs {
+		if ref.Group != nil && *ref.Group != "" || ref.Kind != nil && *ref.Kind != "Service" {
+			continue
+		}
+		if ref.Namespace != nil && string(*ref.Namespace) != namespace {
+			continue
+		}
+		if ref.Name != c.Stable.Name || c.Stable.Port != nil && (ref.Port == nil || *ref.Port != *c.Stable.Port) {
+			continue
+		}
+		return i
+	}
+	return -1
+}
+
+func (c *Canary) canaryRef(stable apisv1.HTTPBackendRef) apisv1.HTTPBackendRef {
+	ref := *stable.DeepCopy()
+	ref.Name = c.Canary.Name
+	if c.Canary.Port != nil {
+		port := *c.Canary.Port
+		ref.Port = &port
+	}
+	return ref
+}
+
+// pinnedMatches returns the matches of rule narrowed by the headers and cookie
+// of canary, each of them pins the requests on its own.
+func (c *Canary) pinnedMatches(matches []apisv1.HTTPRouteMatch) []apisv1.HTTPRouteMatch {
+	var pins [][]apisv1.HTTPHeaderMatch
+	if len(c.Headers) != 0 {
+		pins = append(pins, c.Headers)
+	}
+	if c.Cookie != nil {
+		regex := apisv1.HeaderMatchRegularExpression
+		pins = append(pins, []apisv1.HTTPHeaderMatch{{
+			Type:  &regex,
+			Name:  headerCookie,
+			Value: fmt.Sprintf(`(^|;\s*)%s=%s(;|$)`, regexp.QuoteMeta(c.Cookie.Name), regexp.QuoteMeta(c.Cookie.Value)),
+		}})
+	}
+	if len(pins) == 0 {
+		return nil
+	}
+
+	if len(matches) == 0 {
+		matches = []apisv1.HTTPRouteMatch{{}}
+	}
+	pinned := make([]apisv1.HTTPRouteMatch, 0, len(matches)*len(pins))
+	for _, match := range matches {
+		for _, headers := range pins {
+			m := match.DeepCopy()
+			m.Headers = append(m.Headers, headers...)
+			pinned = append(pinned, *m)
+		}
+	}
+	return pinned
+}
+
+// reduceWeights divides the weights by their greatest common divisor, and
+// scales them down proportionally if any is still above the maximum weight of
+// backendRefs. A weight above zero is kept above zero.
+func reduceWeights(weights []int64) []int32 {
+	var divisor, largest int64
+	for _, weight := range weights {
+		divisor = gcd(divisor, weight)
+		largest = max(largest, weight)
+	}
+	reduced := make([]int32, len(weights))
+	if divisor == 0 {
+		return reduced
+	}
+	largest /= divisor
+	for i, weight := range weights {
+		weight /= divisor
+		if largest > maxBackendWeight {
+			scaled := (weight*maxBackendWeight + largest/2) / largest
+			if scaled == 0 && weight != 0 {
+				scaled = 1
+			}
+			weight = scaled
+		}
+		reduced[i] = int32(weight)
+	}
+	return reduced
+}
+
+func gcd(a, b int64) int64 {
+	for b != 0 {
+		a, b = b, a%b
+	}
+	return a
+}
+
+func backendWeight(ref apisv1.HTTPBackendRef) int32 {
+	if ref.Weight == nil {
+		return 1
+	}
+	return *ref.Weight
+}
+
+// ValidateCanary checks the spec of canary against the baseline rules of route.
+func ValidateCanary(canary *Canary, namespace string) field.ErrorList {
+	var errs field.ErrorList
+	validateBackend := func(path *field.Path, backend CanaryBackend) {
+		for _, msg := range validation.IsDNS1035Label(string(backend.Name)) {
+			errs = append(errs, field.Invalid(path.Child("name"), backend.Name, msg))
+		}
+	}
+	validateBackend(field.NewPath("stable"), canary.Stable)
+	validateBackend(field.NewPath("canary"), canary.Canary)
+	if canary.Stable.Name == canary.Canary.Name && (canary.Canary.Port == nil || canary.Stable.Port != nil && *canary.Stable.Port == *canary.Canary.Port) {
+		errs = append(errs, field.Invalid(field.NewPath("canary"), canary.Canary, "must differ from stable"))
+	}
+
+	validateWeight := func(path *field.Path, weight int32) {
+		if weight < 0 || weight > weightScale {
+			errs = append(errs, field.Invalid(path, weight, fmt.Sprintf("must be between 0 and %d", weightScale)))
+		}
+	}
+	validateWeight(field.NewPath("weight"), canary.Weight)
+	for i, step := range canary.Steps {
+		validateWeight(field.NewPath("steps").Index(i).Child("weight"), step.Weight)
+		if step.Pause.Duration < 0 {
+			errs = append(errs, field.Invalid(field.NewPath("steps").Index(i).Child("pause"), step.Pause.Duration.String(), "must not be negative"))
+		}
+	}
+
+	for i, header := range canary.Headers {
+		if header.Name == "" {
+			errs = append(errs, field.Required(field.NewPath("headers").Index(i).Child("name"), ""))
+		}
+	}
+	if canary.Cookie != nil && canary.Cookie.Name == "" {
+		errs = append(errs, field.Required(field.NewPath("cookie", "name"), ""))
+	}
+
+	routed := false
+	for i := range canary.Baseline {
+		if canary.stableIndex(namespace, &canary.Baseline[i]) >= 0 {
+			routed = true
+			break
+		}
+	}
+	if !routed {
+		errs = append(errs, field.Invalid(field.NewPath("stable"), canary.Stable, fmt.Sprintf("no rule of route has a backendRef to Service %s", canary.Stable.Name)))
+		return errs
+	}
+
+	// the most rules and matches are rendered until the canary succeeds
+	progressing := *canary
+	progressing.Status.Phase = CanaryProgressing
+	return append(errs, validateRules(progressing.render(namespace))...)
+}
+
+// validateRules checks the rendered rules of route against the limits of
+// HTTPRoute, which the update of route would be rejected by.
+func validateRules(rules []apisv1.HTTPRouteRule) field.ErrorList {
+	var errs field.ErrorList
+	rulesPath := field.NewPath("spec", "rules")
+	if len(rules) > maxRouteRules {
+		errs = append(errs, field.TooMany(rulesPath, len(rules), maxRouteRules))
+	}
+	for i, rule := range rules {
+		if len(rule.Matches) > maxRuleMatches {
+			errs = append(errs, field.TooMany(rulesPath.Index(i).Child("matches"), len(rule.Matches), maxRuleMatches))
+		}
+	}
+	return errs
+}
@@ -0,0 +1,343 @@
+package gatewayutil
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+	apisv1 "sigs.k8s.io/gateway-api/apis/v1"
+)
+
+func TestCanaryAdvance(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	steps := []CanaryStep{
+		{Weight: 10, Pause: metav1.Duration{Duration: time.Minute}},
+		{Weight: 50, Pause: metav1.Duration{Duration: 2 * time.Minute}},
+		{Weight: 100},
+	}
+	tests := []struct {
+		name        string
+		steps       []CanaryStep
+		status      CanaryStatus
+		now         time.Time
+		wantChanged bool
+		wantWait    time.Duration
+		wantStatus  CanaryStatus
+	}{
+		{
+			name:       "pause not expired",
+			steps:      steps,
+			status:     CanaryStatus{Phase: CanaryProgressing, Step: 0, Weight: 10, LastTransitionTime: metav1.NewTime(start)},
+			now:        start.Add(20 * time.Second),
+			wantWait:   40 * time.Second,
+			wantStatus: CanaryStatus{Phase: CanaryProgressing, Step: 0, Weight: 10, LastTransitionTime: metav1.NewTime(start)},
+		},
+		{
+			name:        "pause expired",
+			steps:       steps,
+			status:      CanaryStatus{Phase: CanaryProgressing, Step: 0, Weight: 10, LastTransitionTime: metav1.NewTime(start)},
+			now:         start.Add(time.Minute),
+			wantChanged: true,
+			wantWait:    2 * time.Minute,
+			wantStatus:  CanaryStatus{Phase: CanaryProgressing, Step: 1, Weight: 50, LastTransitionTime: metav1.NewTime(start.Add(time.Minute))},
+		},
+		{
+			name:        "last step shifting all traffic succeeds",
+			steps:       steps,
+			status:      CanaryStatus{Phase: CanaryProgressing, Step: 2, Weight: 100, LastTransitionTime: metav1.NewTime(start)},
+			now:         start,
+			wantChanged: true,
+			wantStatus:  CanaryStatus{Phase: CanarySucceeded, Step: 2, Weight: 100, LastTransitionTime: metav1.NewTime(start)},
+		},
+		{
+			name:        "last step shifting part of traffic holds",
+			steps:       steps[:2],
+			status:      CanaryStatus{Phase: CanaryProgressing, Step: 1, Weight: 50, LastTransitionTime: metav1.NewTime(start)},
+			now:         start.Add(3 * time.Minute),
+			wantChanged: true,
+			wantStatus:  CanaryStatus{Phase: CanaryHolding, Step: 1, Weight: 50, LastTransitionTime: metav1.NewTime(start.Add(3 * time.Minute))},
+		},
+		{
+			name:       "holding canary is not advanced",
+			steps:      steps,
+			status:     CanaryStatus{Phase: CanaryHolding, Step: 1, Weight: 50, LastTransitionTime: metav1.NewTime(start)},
+			now:        start.Add(time.Hour),
+			wantStatus: CanaryStatus{Phase: CanaryHolding, Step: 1, Weight: 50, LastTransitionTime: metav1.NewTime(start)},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			canary := &Canary{CanarySpec: CanarySpec{Steps: tt.steps}, Status: tt.status}
+			changed, wait := canary.Advance(tt.now)
+			if changed != tt.wantChanged || wait != tt.wantWait {
+				t.Errorf("Advance() = %v, %v, want %v, %v", changed, wait, tt.wantChanged, tt.wantWait)
+			}
+			if !reflect.DeepEqual(canary.Status, tt.wantStatus) {
+				t.Errorf("Advance() status = %+v, want %+v", canary.Status, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestCanaryStart(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name string
+		spec CanarySpec
+		want CanaryStatus
+	}{
+		{
+			name: "steps",
+			spec: CanarySpec{Weight: 80, Steps: []CanaryStep{{Weight: 20}, {Weight: 100}}},
+			want: CanaryStatus{Phase: CanaryProgressing, Weight: 20, LastTransitionTime: metav1.NewTime(now)},
+		},
+		{
+			name: "weight",
+			spec: CanarySpec{Weight: 30},
+			want: CanaryStatus{Phase: CanaryHolding, Weight: 30, LastTransitionTime: metav1.NewTime(now)},
+		},
+		{
+			name: "all traffic",
+			spec: CanarySpec{Weight: 100},
+			want: CanaryStatus{Phase: CanarySucceeded, Weight: 100, LastTransitionTime: metav1.NewTime(now)},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			canary := &Canary{CanarySpec: tt.spec}
+			canary.Start(now)
+			if !reflect.DeepEqual(canary.Status, tt.want) {
+				t.Errorf("Start() status = %+v, want %+v", canary.Status, tt.want)
+			}
+		})
+	}
+}
+
+func TestCanaryRenderWeights(t *testing.T) {
+	tests := []struct {
+		name     string
+		backends []apisv1.HTTPBackendRef
+		weight   int32
+		phase    string
+		// want are the names and weights of the backendRefs of the rule
+		want []string
+		// wantWeights are the weights of the backendRefs of the rule
+		wantWeights []int32
+	}{
+		{
+			name:        "single backend",
+			backends:    []apisv1.HTTPBackendRef{backendRef("stable", nil)},
+			weight:      20,
+			want:        []string{"stable", "canary"},
+			wantWeights: []int32{4, 1},
+		},
+		{
+			name:        "several backends keep their proportions",
+			backends:    []apisv1.HTTPBackendRef{backendRef("stable", nil), backendRef("other", ptr.To[int32](3))},
+			weight:      20,
+			want:        []string{"stable", "other", "canary"},
+			wantWeights: []int32{4, 15, 1},
+		},
+		{
+			name:        "large weights are reduced by their divisor",
+			backends:    []apisv1.HTTPBackendRef{backendRef("stable", ptr.To[int32](1000000)), backendRef("other", ptr.To[int32](500000))},
+			weight:      30,
+			want:        []string{"stable", "other", "canary"},
+			wantWeights: []int32{7, 5, 3},
+		},
+		{
+			name:        "large weights are scaled down to the maximum",
+			backends:    []apisv1.HTTPBackendRef{backendRef("stable", ptr.To[int32](999983)), backendRef("other", ptr.To[int32](1))},
+			weight:      1,
+			want:        []string{"stable", "other", "canary"},
+			wantWeights: []int32{1000000, 1, 10101},
+		},
+		{
+			name:        "no traffic to canary",
+			backends:    []apisv1.HTTPBackendRef{backendRef("stable", nil), backendRef("other", ptr.To[int32](0))},
+			weight:      0,
+			want:        []string{"stable", "other", "canary"},
+			wantWeights: []int32{1, 0, 0},
+		},
+		{
+			name:        "succeeded canary replaces stable",
+			backends:    []apisv1.HTTPBackendRef{backendRef("stable", ptr.To[int32](2)), backendRef("other", ptr.To[int32](3))},
+			weight:      100,
+			phase:       CanarySucceeded,
+			want:        []string{"canary", "other"},
+			wantWeights: []int32{2, 3},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			phase := tt.phase
+			if phase == "" {
+				phase = CanaryHolding
+			}
+			canary := &Canary{
+				CanarySpec: CanarySpec{Stable: CanaryBackend{Name: "stable"}, Canary: CanaryBackend{Name: "canary"}},
+				Status:     CanaryStatus{Phase: phase, Weight: tt.weight},
+				Baseline:   []apisv1.HTTPRouteRule{{BackendRefs: tt.backends}},
+			}
+			rules := canary.render("default")
+			if len(rules) != 1 {
+				t.Fatalf("render() returns %d rules, want 1", len(rules))
+			}
+			names := make([]string, 0)
+			weights := make([]int32, 0)
+			for _, ref := range rules[0].BackendRefs {
+				names = append(names, string(ref.Name))
+				weights = append(weights, backendWeight(ref))
+				if backendWeight(ref) > maxBackendWeight {
+					t.Errorf("weight %d of %s is above the maximum", backendWeight(ref), ref.Name)
+				}
+			}
+			if !reflect.DeepEqual(names, tt.want) || !reflect.DeepEqual(weights, tt.wantWeights) {
+				t.Errorf("render() backendRefs = %v %v, want %v %v", names, weights, tt.want, tt.wantWeights)
+			}
+		})
+	}
+}
+
+func TestCanaryPinnedMatches(t *testing.T) {
+	exact := apisv1.PathMatchExact
+	regex := apisv1.HeaderMatchRegularExpression
+	path := apisv1.HTTPPathMatch{Type: &exact, Value: ptr.To("/api")}
+	headers := []apisv1.HTTPHeaderMatch{{Name: "X-Canary", Value: "true"}}
+	cookie := apisv1.HTTPHeaderMatch{Type: &regex, Name: headerCookie, Value: `(^|;\s*)user\.group=beta(;|$)`}
+	tests := []struct {
+		name    string
+		headers []apisv1.HTTPHeaderMatch
+		cookie  *CanaryCookie
+		matches []apisv1.HTTPRouteMatch
+		want    []apisv1.HTTPRouteMatch
+	}{
+		{
+			name:    "nothing pinned",
+			matches: []apisv1.HTTPRouteMatch{{Path: &path}},
+		},
+		{
+			name:    "headers narrow every match",
+			headers: headers,
+			matches: []apisv1.HTTPRouteMatch{{Path: &path}, {Method: ptr.To(apisv1.HTTPMethodGet)}},
+			want: []apisv1.HTTPRouteMatch{
+				{Path: &path, Headers: headers},
+				{Method: ptr.To(apisv1.HTTPMethodGet), Headers: headers},
+			},
+		},
+		{
+			name:    "headers and cookie pin on their own",
+			headers: headers,
+			cookie:  &CanaryCookie{Name: "user.group", Value: "beta"},
+			matches: []apisv1.HTTPRouteMatch{{Path: &path}},
+			want: []apisv1.HTTPRouteMatch{
+				{Path: &path, Headers: headers},
+				{Path: &path, Headers: []apisv1.HTTPHeaderMatch{cookie}},
+			},
+		},
+		{
+			name:   "rule without matches",
+			cookie: &CanaryCookie{Name: "user.group", Value: "beta"},
+			want:   []apisv1.HTTPRouteMatch{{Headers: []apisv1.HTTPHeaderMatch{cookie}}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			canary := &Canary{CanarySpec: CanarySpec{Headers: tt.headers, Cookie: tt.cookie}}
+			got := canary.pinnedMatches(tt.matches)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("pinnedMatches() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateCanary(t *testing.T) {
+	baseline := []apisv1.HTTPRouteRule{{BackendRefs: []apisv1.HTTPBackendRef{backendRef("stable", nil)}}}
+	manyRules := make([]apisv1.HTTPRouteRule, 9)
+	for i := range manyRules {
+		manyRules[i] = baseline[0]
+	}
+	manyMatches := []apisv1.HTTPRouteRule{{
+		Matches:     make([]apisv1.HTTPRouteMatch, 33),
+		BackendRefs: []apisv1.HTTPBackendRef{backendRef("stable", nil)},
+	}}
+	pinned := CanarySpec{Stable: CanaryBackend{Name: "stable"}, Canary: CanaryBackend{Name: "canary"},
+		Headers: []apisv1.HTTPHeaderMatch{{Name: "X-Canary", Value: "true"}}, Cookie: &CanaryCookie{Name: "beta"}}
+	tests := []struct {
+		name string
+		spec CanarySpec
+		// baseline defaults to a rule routing to stable
+		baseline   []apisv1.HTTPRouteRule
+		wantFields []string
+	}{
+		{
+			name: "valid",
+			spec: CanarySpec{Stable: CanaryBackend{Name: "stable"}, Canary: CanaryBackend{Name: "canary"}, Weight: 10,
+				Steps: []CanaryStep{{Weight: 50, Pause: metav1.Duration{Duration: time.Minute}}}, Cookie: &CanaryCookie{Name: "beta"}},
+		},
+		{
+			name:       "same backend",
+			spec:       CanarySpec{Stable: CanaryBackend{Name: "stable"}, Canary: CanaryBackend{Name: "stable"}},
+			wantFields: []string{"canary"},
+		},
+		{
+			name: "invalid weights and pause",
+			spec: CanarySpec{Stable: CanaryBackend{Name: "stable"}, Canary: CanaryBackend{Name: "canary"}, Weight: 101,
+				Steps: []CanaryStep{{Weight: -1, Pause: metav1.Duration{Duration: -time.Second}}}},
+			wantFields: []string{"weight", "steps[0].weight", "steps[0].pause"},
+		},
+		{
+			name: "pinned without names",
+			spec: CanarySpec{Stable: CanaryBackend{Name: "stable"}, Canary: CanaryBackend{Name: "canary"},
+				Headers: []apisv1.HTTPHeaderMatch{{Value: "true"}}, Cookie: &CanaryCookie{Value: "beta"}},
+			wantFields: []string{"headers[0].name", "cookie.name"},
+		},
+		{
+			name:       "stable not routed",
+			spec:       CanarySpec{Stable: CanaryBackend{Name: "other"}, Canary: CanaryBackend{Name: "canary"}},
+			wantFields: []string{"stable"},
+		},
+		{
+			name:       "pinned rules beyond the limit of rules",
+			spec:       pinned,
+			baseline:   manyRules,
+			wantFields: []string{"spec.rules"},
+		},
+		{
+			name:       "pinned matches beyond the limit of matches",
+			spec:       pinned,
+			baseline:   manyMatches,
+			wantFields: []string{"spec.rules[0].matches"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rules := tt.baseline
+			if rules == nil {
+				rules = baseline
+			}
+			errs := ValidateCanary(&Canary{CanarySpec: tt.spec, Baseline: rules}, "default")
+			fields := make([]string, 0, len(errs))
+			for _, err := range errs {
+				fields = append(fields, err.Field)
+			}
+			if len(fields) == 0 && len(tt.wantFields) == 0 {
+				return
+			}
+			if !reflect.DeepEqual(fields, tt.wantFields) {
+				t.Errorf("ValidateCanary() fields = %v, want %v", fields, tt.wantFields)
+			}
+		})
+	}
+}
+
+func backendRef(name string, weight *int32) apisv1.HTTPBackendRef {
+	ref := apisv1.HTTPBackendRef{}
+	ref.Name = apisv1.ObjectName(name)
+	ref.Port = ptr.To[apisv1.PortNumber](80)
+	ref.Weight = weight
+	return ref
+}
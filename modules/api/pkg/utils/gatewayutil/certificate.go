@@ -0,0 +1,131 @@
+package gatewayutil
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+
+	apisv1 "sigs.k8s.io/gateway-api/apis/v1"
+	"software.sslmate.com/src/go-pkcs12"
+)
+
+const (
+	// AnnotationCertificateIssuer asks for the certificates of the listeners
+	// which terminate TLS without certificateRefs to be issued by cert-manager.
+	AnnotationCertificateIssuer = "gatewayapi.kubesphere.io/certificate-issuer"
+	// AnnotationCertificateIssuerKind is the kind of issuer, one of Issuer and
+	// ClusterIssuer, defaults to ClusterIssuer.
+	AnnotationCertificateIssuerKind = "gatewayapi.kubesphere.io/certificate-issuer-kind"
+	// AnnotationCertificateNamespace is the namespace of the Certificates and
+	// their Secrets, which is also the namespace of an Issuer. Defaults to the
+	// namespace of gateway, the caller must be allowed to create Certificates
+	// and ReferenceGrants in another one.
+	AnnotationCertificateNamespace = "gatewayapi.kubesphere.io/certificate-namespace"
+
+	// LabelGatewayNamespace, LabelGatewayName and LabelListener identify the
+	// listener which a Certificate is issued for.
+	LabelGatewayNamespace = "gatewayapi.kubesphere.io/gateway-namespace"
+	LabelGatewayName      = "gatewayapi.kubesphere.io/gateway-name"
+	LabelListener         = "gatewayapi.kubesphere.io/listener"
+)
+
+// TerminatesTLS reports whether the listener terminates TLS, so it needs a
+// certificate.
+func TerminatesTLS(listener *apisv1.Listener) bool {
+	switch listener.Protocol {
+	case apisv1.HTTPSProtocolType:
+		return true
+	case apisv1.TLSProtocolType:
+		return listener.TLS != nil && listener.TLS.Mode != nil && *listener.TLS.Mode == apisv1.TLSModeTerminate
+	default:
+		return false
+	}
+}
+
+// ParseCertificates parses the PEM encoded certificates, the leaf certificate
+// comes first.
+func ParseCertificates(data []byte) ([]*x509.Certificate, error) {
+	certificates := make([]*x509.Certificate, 0)
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		certificate, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		certificates = append(certificates, certificate)
+	}
+	if len(certificates) == 0 {
+		return nil, fmt.Errorf("no certificate found in PEM data")
+	}
+	return certificates, nil
+}
+
+// ParseKeyPair parses the PEM encoded certificate chain and private key, and
+// verifies that the leaf certificate matches the key and every certificate of
+// the chain is signed by the next one.
+func ParseKeyPair(certPEM, keyPEM []byte) ([]*x509.Certificate, error) {
+	if _, err := tls.X509KeyPair(certPEM, keyPEM); err != nil {
+		return nil, err
+	}
+	chain, err := ParseCertificates(certPEM)
+	if err != nil {
+		return nil, err
+	}
+	for i := 0; i < len(chain)-1; i++ {
+		if err = chain[i].CheckSignatureFrom(chain[i+1]); err != nil {
+			return nil, fmt.Errorf("certificate %q is not signed by the next certificate %q of chain: %v", chain[i].Subject, chain[i+1].Subject, err)
+		}
+	}
+	return chain, nil
+}
+
+// ConvertPKCS12 converts the PKCS #12 bundle to the PEM encoded certificate
+// chain and private key. The certificate of the key comes first, followed by
+// its issuers in the bundle.
+func ConvertPKCS12(data []byte, password string) (certPEM, keyPEM []byte, err error) {
+	// DecodeChain supports the AES based encryptions used by OpenSSL 3 by default
+	privateKey, leaf, certificates, err := pkcs12.DecodeChain(data, password)
+	if err != nil {
+		return nil, nil, err
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(privateKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	keyPEM = pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+	if _, err = tls.X509KeyPair(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: leaf.Raw}), keyPEM); err != nil {
+		return nil, nil, fmt.Errorf("no certificate of the private key found in PKCS #12 bundle: %v", err)
+	}
+
+	chain := []*x509.Certificate{leaf}
+	for len(certificates) != 0 {
+		last := chain[len(chain)-1]
+		next := -1
+		for i, certificate := range certificates {
+			if bytes.Equal(last.RawIssuer, certificate.RawSubject) && !bytes.Equal(last.Raw, certificate.Raw) {
+				next = i
+				break
+			}
+		}
+		if next < 0 {
+			// the certificates which are not issuers of chain are dropped
+			break
+		}
+		chain = append(chain, certificates[next])
+		certificates = append(certificates[:next], certificates[next+1:]...)
+	}
+
+	for _, certificate := range chain {
+		certPEM = append(certPEM, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certificate.Raw})...)
+	}
+	return certPEM, keyPEM, nil
+}
@@ -0,0 +1,124 @@
+package gatewayutil
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"testing"
+	"time"
+
+	"software.sslmate.com/src/go-pkcs12"
+)
+
+func TestConvertPKCS12(t *testing.T) {
+	root, rootKey := newCertificate(t, "root", nil, nil)
+	intermediate, intermediateKey := newCertificate(t, "intermediate", root, rootKey)
+	leaf, leafKey := newCertificate(t, "leaf", intermediate, intermediateKey)
+	other, _ := newCertificate(t, "other", nil, nil)
+
+	tests := []struct {
+		name     string
+		encoder  *pkcs12.Encoder
+		caCerts  []*x509.Certificate
+		password string
+		// want are the common names of the converted chain
+		want    []string
+		wantErr bool
+	}{
+		{
+			name:     "AES encrypted bundle",
+			encoder:  pkcs12.Modern,
+			caCerts:  []*x509.Certificate{root, intermediate},
+			password: "secret",
+			want:     []string{"leaf", "intermediate", "root"},
+		},
+		{
+			name:     "legacy encrypted bundle",
+			encoder:  pkcs12.LegacyRC2,
+			caCerts:  []*x509.Certificate{intermediate},
+			password: "secret",
+			want:     []string{"leaf", "intermediate"},
+		},
+		{
+			name:     "certificates out of chain are dropped",
+			encoder:  pkcs12.Modern,
+			caCerts:  []*x509.Certificate{other, intermediate},
+			password: "secret",
+			want:     []string{"leaf", "intermediate"},
+		},
+		{
+			name:     "wrong password",
+			encoder:  pkcs12.Modern,
+			password: "wrong",
+			wantErr:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := tt.encoder.Encode(leafKey, leaf, tt.caCerts, "secret")
+			if err != nil {
+				t.Fatal(err)
+			}
+			certPEM, keyPEM, err := ConvertPKCS12(data, tt.password)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ConvertPKCS12() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			chain, err := ParseKeyPair(certPEM, keyPEM)
+			if err != nil {
+				t.Fatalf("ParseKeyPair() error = %v", err)
+			}
+			var got []string
+			for _, certificate := range chain {
+				got = append(got, certificate.Subject.CommonName)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("ConvertPKCS12() chain = %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Fatalf("ConvertPKCS12() chain = %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+// newCertificate issues a certificate by the issuer, it is self signed if
+// issuer is nil.
+func newCertificate(t *testing.T, commonName string, issuer *x509.Certificate, issuerKey *ecdsa.PrivateKey) (*x509.Certificate, *ecdsa.PrivateKey) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	serial, err := rand.Int(rand.Reader, big.NewInt(1<<62))
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{CommonName: commonName},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  issuer == nil || commonName != "leaf",
+	}
+	if issuer == nil {
+		issuer, issuerKey = template, key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, issuer, &key.PublicKey, issuerKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certificate, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return certificate, key
+}
@@ -0,0 +1,120 @@
+package gatewayutil
+
+import (
+	"context"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	apisv1 "sigs.k8s.io/gateway-api/apis/v1"
+)
+
+const (
+	// ConflictReasonProtocol is the reason of listeners whose protocols can
+	// not share a port.
+	ConflictReasonProtocol = "ProtocolConflict"
+	// ConflictReasonHostname is the reason of listeners with the same protocol
+	// whose hostnames may match the same request.
+	ConflictReasonHostname = "HostnameConflict"
+)
+
+// ListenerConflict is a listener of gateway which conflicts with a listener of
+// another gateway of the same GatewayClass.
+type ListenerConflict struct {
+	Listener apisv1.SectionName  `json:"listener"`
+	Port     apisv1.PortNumber   `json:"port"`
+	Protocol apisv1.ProtocolType `json:"protocol"`
+	Hostname string              `json:"hostname,omitempty"`
+
+	Reason  string `json:"reason"`
+	Message string `json:"message"`
+
+	// ConflictingGateway and ConflictingListener are the listener which the
+	// listener conflicts with
+	ConflictingGateway  ObjectReference     `json:"conflictingGateway"`
+	ConflictingListener apisv1.SectionName  `json:"conflictingListener"`
+	ConflictingProtocol apisv1.ProtocolType `json:"conflictingProtocol"`
+	ConflictingHostname string              `json:"conflictingHostname,omitempty"`
+
+	// index is the index of listener in the gateway
+	index int
+}
+
+type ObjectReference struct {
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+}
+
+// FieldError returns the field error of the conflicting listener.
+func (c ListenerConflict) FieldError() *field.Error {
+	path := field.NewPath("spec", "listeners").Index(c.index)
+	if c.Reason == ConflictReasonProtocol {
+		return field.Invalid(path.Child("protocol"), c.Protocol, c.Message)
+	}
+	return field.Invalid(path.Child("hostname"), c.Hostname, c.Message)
+}
+
+// FindListenerConflicts checks the listeners of gateway against the listeners
+// of every other gateway of the same GatewayClass, which share the ports of the
+// data plane. Listeners on the same port conflict if their protocols can not
+// share the port, or if they have the same protocol and overlapping hostnames.
+func FindListenerConflicts(ctx context.Context, reader client.Reader, gateway *apisv1.Gateway) ([]ListenerConflict, error) {
+	list := &apisv1.GatewayList{}
+	err := reader.List(ctx, list, client.InNamespace(""))
+	if err != nil {
+		return nil, err
+	}
+
+	conflicts := make([]ListenerConflict, 0)
+	for _, other := range list.Items {
+		if other.Spec.GatewayClassName != gateway.Spec.GatewayClassName {
+			continue
+		}
+		if other.Namespace == gateway.Namespace && other.Name == gateway.Name {
+			continue
+		}
+		for i, listener := range gateway.Spec.Listeners {
+			for _, otherListener := range other.Spec.Listeners {
+				if listener.Port != otherListener.Port {
+					continue
+				}
+				conflict := ListenerConflict{
+					Listener:            listener.Name,
+					Port:                listener.Port,
+					Protocol:            listener.Protocol,
+					Hostname:            hostnameOf(listener.Hostname),
+					ConflictingGateway:  ObjectReference{Namespace: other.Namespace, Name: other.Name},
+					ConflictingListener: otherListener.Name,
+					ConflictingProtocol: otherListener.Protocol,
+					ConflictingHostname: hostnameOf(otherListener.Hostname),
+					index:               i,
+				}
+				switch {
+				case !protocolsCompatible(listener.Protocol, otherListener.Protocol):
+					conflict.Reason = ConflictReasonProtocol
+					conflict.Message = fmt.Sprintf("conflicts with protocol %s of listener %s of gateway %s/%s on port %d",
+						otherListener.Protocol, otherListener.Name, other.Namespace, other.Name, listener.Port)
+				case sharesHostnames(listener.Protocol, otherListener.Protocol) && HostnamesOverlap(conflict.Hostname, conflict.ConflictingHostname):
+					conflict.Reason = ConflictReasonHostname
+					conflict.Message = fmt.Sprintf("overlaps with hostname %q of listener %s of gateway %s/%s on port %d",
+						conflict.ConflictingHostname, otherListener.Name, other.Namespace, other.Name, listener.Port)
+				default:
+					continue
+				}
+				conflicts = append(conflicts, conflict)
+			}
+		}
+	}
+	return conflicts, nil
+}
+
+// sharesHostnames reports whether listeners of two protocols on the same port
+// tell requests apart by the same hostnames, HTTPS and TLS listeners are both
+// routed by SNI.
+func sharesHostnames(a, b apisv1.ProtocolType) bool {
+	if a == b {
+		return true
+	}
+	tlsProtocols := map[apisv1.ProtocolType]bool{apisv1.HTTPSProtocolType: true, apisv1.TLSProtocolType: true}
+	return tlsProtocols[a] && tlsProtocols[b]
+}
@@ -0,0 +1,182 @@
+package gatewayutil
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	apisv1 "sigs.k8s.io/gateway-api/apis/v1"
+)
+
+func TestProtocolsCompatible(t *testing.T) {
+	tests := []struct {
+		a, b apisv1.ProtocolType
+		want bool
+	}{
+		{apisv1.HTTPProtocolType, apisv1.HTTPProtocolType, true},
+		{apisv1.HTTPProtocolType, apisv1.HTTPSProtocolType, false},
+		{apisv1.HTTPProtocolType, apisv1.TCPProtocolType, false},
+		{apisv1.HTTPSProtocolType, apisv1.TLSProtocolType, true},
+		{apisv1.TLSProtocolType, apisv1.TCPProtocolType, false},
+		{apisv1.TCPProtocolType, apisv1.UDPProtocolType, true},
+		{apisv1.UDPProtocolType, apisv1.HTTPProtocolType, true},
+		{apisv1.UDPProtocolType, apisv1.UDPProtocolType, true},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.a)+"|"+string(tt.b), func(t *testing.T) {
+			if got := protocolsCompatible(tt.a, tt.b); got != tt.want {
+				t.Errorf("protocolsCompatible(%s, %s) = %v, want %v", tt.a, tt.b, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFindListenerConflicts(t *testing.T) {
+	other := newGateway("other", "class",
+		listener("http", apisv1.HTTPProtocolType, 80, "a.foo.com"),
+		listener("https", apisv1.HTTPSProtocolType, 443, "*.foo.com"),
+		listener("dns", apisv1.UDPProtocolType, 53, ""),
+	)
+	otherClass := newGateway("other-class", "another",
+		listener("http", apisv1.HTTPProtocolType, 80, ""),
+	)
+	tests := []struct {
+		name      string
+		listeners []apisv1.Listener
+		// want are the listener, reason and conflicting gateway of conflicts
+		want []string
+	}{
+		{
+			name: "no conflict",
+			listeners: []apisv1.Listener{
+				listener("http", apisv1.HTTPProtocolType, 80, "b.foo.com"),
+				listener("tls", apisv1.TLSProtocolType, 443, "bar.com"),
+				listener("dns", apisv1.TCPProtocolType, 53, ""),
+				listener("web", apisv1.HTTPProtocolType, 8080, ""),
+			},
+		},
+		{
+			name: "protocol conflict",
+			listeners: []apisv1.Listener{
+				listener("tcp", apisv1.TCPProtocolType, 80, ""),
+			},
+			want: []string{"tcp/" + ConflictReasonProtocol + "/default/other"},
+		},
+		{
+			name: "hostname conflict",
+			listeners: []apisv1.Listener{
+				listener("http", apisv1.HTTPProtocolType, 80, ""),
+				listener("tls", apisv1.TLSProtocolType, 443, "a.foo.com"),
+			},
+			want: []string{
+				"http/" + ConflictReasonHostname + "/default/other",
+				"tls/" + ConflictReasonHostname + "/default/other",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scheme := runtime.NewScheme()
+			_ = apisv1.Install(scheme)
+			gateway := newGateway("gateway", "class", tt.listeners...)
+			// the gateway itself is skipped
+			reader := fake.NewClientBuilder().WithScheme(scheme).WithObjects(other, otherClass, gateway.DeepCopy()).Build()
+
+			conflicts, err := FindListenerConflicts(context.Background(), reader, gateway)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := make([]string, 0, len(conflicts))
+			for _, conflict := range conflicts {
+				got = append(got, string(conflict.Listener)+"/"+conflict.Reason+"/"+conflict.ConflictingGateway.Namespace+"/"+conflict.ConflictingGateway.Name)
+			}
+			if len(got) != 0 || len(tt.want) != 0 {
+				if !reflect.DeepEqual(got, tt.want) {
+					t.Errorf("FindListenerConflicts() = %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestValidateGatewayConflicts(t *testing.T) {
+	class := &apisv1.GatewayClass{ObjectMeta: metav1.ObjectMeta{Name: "class", Annotations: map[string]string{
+		AnnotationListenerTemplates: "version: v1alpha1\nlisteners: [{name: web, protocols: [HTTP, TCP]}]",
+	}}}
+	other := newGateway("other", "class", listener("http", apisv1.HTTPProtocolType, 80, ""))
+	existing := newGateway("gateway", "class", listener("http", apisv1.HTTPProtocolType, 80, "a.foo.com"))
+	tests := []struct {
+		name         string
+		listeners    []apisv1.Listener
+		old          *apisv1.Gateway
+		wantErrors   int
+		wantWarnings int
+	}{
+		{
+			name:       "conflict on creation",
+			listeners:  []apisv1.Listener{listener("http", apisv1.HTTPProtocolType, 80, "a.foo.com")},
+			wantErrors: 1,
+		},
+		{
+			name: "existing conflict of unchanged listener on update",
+			listeners: []apisv1.Listener{
+				listener("http", apisv1.HTTPProtocolType, 80, "a.foo.com"),
+				listener("web", apisv1.HTTPProtocolType, 8080, ""),
+			},
+			old:          existing,
+			wantWarnings: 1,
+		},
+		{
+			name:       "conflict of changed listener on update",
+			listeners:  []apisv1.Listener{listener("http", apisv1.HTTPProtocolType, 80, "b.foo.com")},
+			old:        existing,
+			wantErrors: 1,
+		},
+		{
+			name:       "conflict of new listener on update",
+			listeners:  []apisv1.Listener{listener("tcp", apisv1.TCPProtocolType, 80, "")},
+			old:        existing,
+			wantErrors: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scheme := runtime.NewScheme()
+			_ = apisv1.Install(scheme)
+			reader := fake.NewClientBuilder().WithScheme(scheme).WithObjects(class, other).Build()
+			gateway := newGateway("gateway", "class", tt.listeners...)
+			gateway.Labels = map[string]string{LabelScope: ScopeCluster}
+
+			result, err := ValidateGateway(context.Background(), reader, gateway, tt.old)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(result.Errors) != tt.wantErrors || len(result.Warnings) != tt.wantWarnings {
+				t.Errorf("ValidateGateway() errors = %v, warnings = %v, want %d errors and %d warnings",
+					result.Errors, result.Warnings, tt.wantErrors, tt.wantWarnings)
+			}
+		})
+	}
+}
+
+func newGateway(name, className string, listeners ...apisv1.Listener) *apisv1.Gateway {
+	return &apisv1.Gateway{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: name},
+		Spec: apisv1.GatewaySpec{
+			GatewayClassName: apisv1.ObjectName(className),
+			Listeners:        listeners,
+		},
+	}
+}
+
+func listener(name string, protocol apisv1.ProtocolType, port apisv1.PortNumber, hostname string) apisv1.Listener {
+	l := apisv1.Listener{Name: apisv1.SectionName(name), Protocol: protocol, Port: port}
+	if hostname != "" {
+		h := apisv1.Hostname(hostname)
+		l.Hostname = &h
+	}
+	return l
+}
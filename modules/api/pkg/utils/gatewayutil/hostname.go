@@ -0,0 +1,56 @@
+package gatewayutil
+
+import (
+	"strings"
+
+	apisv1 "sigs.k8s.io/gateway-api/apis/v1"
+)
+
+// HostnamesOverlap reports whether two hostnames may match the same request,
+// an empty hostname matches all hostnames and a wildcard hostname "*.foo.com"
+// matches every hostname with one or more labels before "foo.com".
+func HostnamesOverlap(a, b string) bool {
+	if a == "" || b == "" || a == b {
+		return true
+	}
+	aWildcard, bWildcard := strings.HasPrefix(a, "*."), strings.HasPrefix(b, "*.")
+	switch {
+	case aWildcard && bWildcard:
+		return strings.HasSuffix(a[1:], b[1:]) || strings.HasSuffix(b[1:], a[1:])
+	case aWildcard:
+		return strings.HasSuffix(b, a[1:])
+	case bWildcard:
+		return strings.HasSuffix(a, b[1:])
+	default:
+		return false
+	}
+}
+
+// IntersectHostnames returns the hostnames of route which a listener with the
+// hostname accepts, the more specific one of each overlapping pair is returned.
+// A route without hostnames is attached with the hostname of listener.
+func IntersectHostnames(listenerHostname *apisv1.Hostname, routeHostnames []apisv1.Hostname) []apisv1.Hostname {
+	if listenerHostname == nil || *listenerHostname == "" {
+		return routeHostnames
+	}
+	if len(routeHostnames) == 0 {
+		return []apisv1.Hostname{*listenerHostname}
+	}
+
+	hostnames := make([]apisv1.Hostname, 0, len(routeHostnames))
+	for _, hostname := range routeHostnames {
+		if !HostnamesOverlap(string(*listenerHostname), string(hostname)) {
+			continue
+		}
+		if strings.HasPrefix(string(hostname), "*.") && !strings.HasPrefix(string(*listenerHostname), "*.") {
+			hostnames = append(hostnames, *listenerHostname)
+			continue
+		}
+		if strings.HasPrefix(string(hostname), "*.") && len(*listenerHostname) > len(hostname) {
+			hostnames = append(hostnames, *listenerHostname)
+			continue
+		}
+		hostnames = append(hostnames, hostname)
+	}
+	return hostnames
+}
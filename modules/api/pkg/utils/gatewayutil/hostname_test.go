@@ -0,0 +1,91 @@
+package gatewayutil
+
+import (
+	"reflect"
+	"testing"
+
+	apisv1 "sigs.k8s.io/gateway-api/apis/v1"
+)
+
+func TestHostnamesOverlap(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want bool
+	}{
+		{"", "foo.com", true},
+		{"foo.com", "", true},
+		{"foo.com", "foo.com", true},
+		{"foo.com", "bar.com", false},
+		{"*.foo.com", "a.foo.com", true},
+		{"*.foo.com", "a.b.foo.com", true},
+		{"*.foo.com", "foo.com", false},
+		{"a.foo.com", "*.foo.com", true},
+		{"*.foo.com", "*.a.foo.com", true},
+		{"*.a.foo.com", "*.foo.com", true},
+		{"*.foo.com", "*.bar.com", false},
+		{"*.foo.com", "afoo.com", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.a+"|"+tt.b, func(t *testing.T) {
+			if got := HostnamesOverlap(tt.a, tt.b); got != tt.want {
+				t.Errorf("HostnamesOverlap(%q, %q) = %v, want %v", tt.a, tt.b, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIntersectHostnames(t *testing.T) {
+	hostname := func(h string) *apisv1.Hostname {
+		hostname := apisv1.Hostname(h)
+		return &hostname
+	}
+	tests := []struct {
+		name     string
+		listener *apisv1.Hostname
+		route    []apisv1.Hostname
+		want     []apisv1.Hostname
+	}{
+		{
+			name:  "listener without hostname accepts all",
+			route: []apisv1.Hostname{"a.foo.com", "*.bar.com"},
+			want:  []apisv1.Hostname{"a.foo.com", "*.bar.com"},
+		},
+		{
+			name:     "route without hostnames takes the listener hostname",
+			listener: hostname("*.foo.com"),
+			want:     []apisv1.Hostname{"*.foo.com"},
+		},
+		{
+			name:     "exact listener hostname",
+			listener: hostname("a.foo.com"),
+			route:    []apisv1.Hostname{"*.foo.com", "a.foo.com", "b.foo.com"},
+			want:     []apisv1.Hostname{"a.foo.com", "a.foo.com"},
+		},
+		{
+			name:     "wildcard listener hostname",
+			listener: hostname("*.foo.com"),
+			route:    []apisv1.Hostname{"a.foo.com", "*.a.foo.com", "bar.com"},
+			want:     []apisv1.Hostname{"a.foo.com", "*.a.foo.com"},
+		},
+		{
+			name:     "the more specific wildcard",
+			listener: hostname("*.a.foo.com"),
+			route:    []apisv1.Hostname{"*.foo.com"},
+			want:     []apisv1.Hostname{"*.a.foo.com"},
+		},
+		{
+			name:     "no overlap",
+			listener: hostname("*.foo.com"),
+			route:    []apisv1.Hostname{"bar.com"},
+			want:     []apisv1.Hostname{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := IntersectHostnames(tt.listener, tt.route)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("IntersectHostnames() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,52 @@
+package gatewayutil
+
+import (
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	apisv1 "sigs.k8s.io/gateway-api/apis/v1"
+)
+
+const (
+	ScopeNamespace = "namespace"
+	ScopeWorkspace = "workspace"
+	ScopeCluster   = "cluster"
+
+	// LabelScope is the scope of gateway, one of namespace, workspace and cluster.
+	LabelScope = "gatewayapi.kubesphere.io/scope"
+	// LabelWorkingNamespace is the namespace which a namespace scoped gateway works for.
+	LabelWorkingNamespace = "gatewayapi.kubesphere.io/working-namespace"
+	// LabelWorkingWorkspace is the workspace which a workspace scoped gateway works for.
+	LabelWorkingWorkspace = "gatewayapi.kubesphere.io/working-workspace"
+
+	// LabelWorkspace is the workspace label of KubeSphere namespaces.
+	LabelWorkspace = "kubesphere.io/workspace"
+)
+
+// ScopeLabelKeys are the labels which decide the scope of a gateway.
+var ScopeLabelKeys = []string{LabelScope, LabelWorkingWorkspace, LabelWorkingNamespace}
+
+// ValidateScopeLabels checks that the gateway has a valid scope label and the
+// working namespace or workspace label required by its scope.
+func ValidateScopeLabels(gateway *apisv1.Gateway) error {
+	if err := validateScopeLabels(gateway); err != nil {
+		return err
+	}
+	return nil
+}
+
+func validateScopeLabels(gateway *apisv1.Gateway) *field.Error {
+	labelsPath := field.NewPath("metadata", "labels")
+	switch scope := gateway.Labels[LabelScope]; scope {
+	case ScopeNamespace:
+		if gateway.Labels[LabelWorkingNamespace] == "" {
+			return field.Required(labelsPath.Key(LabelWorkingNamespace), "required by namespace scoped gateway")
+		}
+	case ScopeWorkspace:
+		if gateway.Labels[LabelWorkingWorkspace] == "" {
+			return field.Required(labelsPath.Key(LabelWorkingWorkspace), "required by workspace scoped gateway")
+		}
+	case ScopeCluster:
+	default:
+		return field.NotSupported(labelsPath.Key(LabelScope), scope, []string{ScopeNamespace, ScopeWorkspace, ScopeCluster})
+	}
+	return nil
+}
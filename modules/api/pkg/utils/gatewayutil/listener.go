@@ -0,0 +1,375 @@
+package gatewayutil
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	apisv1 "sigs.k8s.io/gateway-api/apis/v1"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	// AnnotationListenerTemplates is the versioned listener templates of
+	// GatewayClass in JSON or YAML, which takes precedence over the legacy
+	// listener annotations.
+	AnnotationListenerTemplates = "gatewayapi.kubesphere.io/listener-templates"
+
+	// ListenerTemplatesVersion is the supported version of listener templates.
+	ListenerTemplatesVersion = "v1alpha1"
+
+	// AnnotationListener and the annotations of each listener are the legacy
+	// listener templates.
+	AnnotationListener          = "gatewayapi.kubesphere.io/listener"
+	AnnotationListenerProtocols = "gatewayapi.kubesphere.io/listener.%s.protocols"
+	AnnotationListenerPort      = "gatewayapi.kubesphere.io/listener.%s.port"
+)
+
+// protocols are the listener protocols which a template may advertise.
+var protocols = []apisv1.ProtocolType{
+	apisv1.HTTPProtocolType,
+	apisv1.HTTPSProtocolType,
+	apisv1.TLSProtocolType,
+	apisv1.TCPProtocolType,
+	apisv1.UDPProtocolType,
+}
+
+// ListenerTemplates is the content of the listener templates annotation.
+type ListenerTemplates struct {
+	Version   string     `json:"version"`
+	Listeners []Listener `json:"listeners"`
+}
+
+// Listener is a listener template advertised by GatewayClass, the listeners of
+// gateways of the class must match one of the templates.
+type Listener struct {
+	Name      string                `json:"name"`
+	Protocols []apisv1.ProtocolType `json:"protocols,omitempty"`
+	// Port is the port of listeners, any port is allowed if neither Port nor
+	// PortRange is set
+	Port      int32      `json:"port,omitempty"`
+	PortRange *PortRange `json:"portRange,omitempty"`
+	// Hostnames are the hostnames which listeners may use, a wildcard hostname
+	// allows the hostnames under it. Any hostname is allowed if it is empty
+	Hostnames []string `json:"hostnames,omitempty"`
+	// TLSModes are the TLS modes which listeners may use, any mode is allowed
+	// if it is empty
+	TLSModes []apisv1.TLSModeType `json:"tlsModes,omitempty"`
+	// AllowedRoutes is the default AllowedRoutes of listeners created from the
+	// template, the namespaces are still limited by the scope of gateway
+	AllowedRoutes *apisv1.AllowedRoutes `json:"allowedRoutes,omitempty"`
+}
+
+type PortRange struct {
+	From int32 `json:"from"`
+	To   int32 `json:"to"`
+}
+
+// HasListenerTemplates reports whether the GatewayClass advertises listener
+// templates, either by the versioned or the legacy annotations.
+func HasListenerTemplates(gatewayClass *apisv1.GatewayClass) bool {
+	if _, ok := gatewayClass.Annotations[AnnotationListenerTemplates]; ok {
+		return true
+	}
+	_, ok := gatewayClass.Annotations[AnnotationListener]
+	return ok
+}
+
+/*
+ParseListeners get the listeners of GatewayClass.
+The gateway need to specific the listener templates by annotations.
+
+Example:
+
+	apiVersion: gateway.networking.k8s.io/v1
+	kind: GatewayClass
+	metadata:
+	 annotations:
+	   gatewayapi.kubesphere.io/listener-templates: |
+	     version: v1alpha1
+	     listeners:
+	     - name: web
+	       protocols: [TCP, HTTP]
+	       port: 8000
+	     - name: websecure
+	       protocols: [TLS, HTTPS]
+	       portRange: {from: 8443, to: 8453}
+	       hostnames: ["*.example.com"]
+	       tlsModes: [Terminate]
+	 name: traefik
+	spec:
+	 controllerName: traefik.io/gateway-controller
+
+The legacy annotations are read if the versioned templates are not set:
+
+	gatewayapi.kubesphere.io/listener: web,websecure
+	gatewayapi.kubesphere.io/listener.web.protocols: tcp,http
+	gatewayapi.kubesphere.io/listener.web.port: '8000'
+	gatewayapi.kubesphere.io/listener.websecure.protocols: tls,https
+	gatewayapi.kubesphere.io/listener.websecure.port: '8443'
+*/
+func ParseListeners(gatewayClass *apisv1.GatewayClass) ([]Listener, error) {
+	anno := gatewayClass.Annotations
+	if content, ok := anno[AnnotationListenerTemplates]; ok {
+		path := field.NewPath("metadata", "annotations").Key(AnnotationListenerTemplates)
+		templates := &ListenerTemplates{}
+		err := yaml.UnmarshalStrict([]byte(content), templates)
+		if err != nil {
+			return nil, field.Invalid(path, content, err.Error())
+		}
+		if templates.Version != ListenerTemplatesVersion {
+			return nil, field.NotSupported(path.Child("version"), templates.Version, []string{ListenerTemplatesVersion})
+		}
+		errs := validateListenerTemplates(path.Child("listeners"), templates.Listeners)
+		if len(errs) != 0 {
+			return nil, errs.ToAggregate()
+		}
+		return templates.Listeners, nil
+	}
+
+	listeners, errs := parseLegacyListeners(anno)
+	if len(errs) != 0 {
+		return nil, errs.ToAggregate()
+	}
+	errs = validateListenerTemplates(field.NewPath("metadata", "annotations").Key(AnnotationListener), listeners)
+	if len(errs) != 0 {
+		return nil, errs.ToAggregate()
+	}
+	return listeners, nil
+}
+
+// parseLegacyListeners reads the comma separated legacy annotations, the
+// values are trimmed and the protocols are case insensitive, which are kept as
+// written.
+func parseLegacyListeners(anno map[string]string) ([]Listener, field.ErrorList) {
+	var errs field.ErrorList
+	annotationsPath := field.NewPath("metadata", "annotations")
+	names := splitList(anno[AnnotationListener])
+	if len(names) == 0 {
+		return nil, append(errs, field.Required(annotationsPath.Key(AnnotationListener), "no listener can be used"))
+	}
+
+	listeners := make([]Listener, 0, len(names))
+	for _, name := range names {
+		listener := Listener{Name: name}
+		protocolsKey := fmt.Sprintf(AnnotationListenerProtocols, name)
+		for _, protocol := range splitList(anno[protocolsKey]) {
+			if _, ok := canonicalProtocol(protocol); !ok {
+				errs = append(errs, field.NotSupported(annotationsPath.Key(protocolsKey), protocol, supportedProtocols()))
+				continue
+			}
+			listener.Protocols = append(listener.Protocols, apisv1.ProtocolType(protocol))
+		}
+
+		portKey := fmt.Sprintf(AnnotationListenerPort, name)
+		if pStr := strings.TrimSpace(anno[portKey]); pStr != "" {
+			port, err := strconv.ParseInt(pStr, 10, 32)
+			if err != nil {
+				errs = append(errs, field.Invalid(annotationsPath.Key(portKey), pStr, "invalid port"))
+				continue
+			}
+			listener.Port = int32(port)
+		}
+		listeners = append(listeners, listener)
+	}
+	return listeners, errs
+}
+
+// validateListenerTemplates checks the listener templates strictly, so that a
+// mistake in the templates is reported instead of refusing every gateway.
+func validateListenerTemplates(path *field.Path, listeners []Listener) field.ErrorList {
+	var errs field.ErrorList
+	if len(listeners) == 0 {
+		return append(errs, field.Required(path, "no listener can be used"))
+	}
+	names := sets.New[string]()
+	for i := range listeners {
+		listener := &listeners[i]
+		listenerPath := path.Index(i)
+		if listener.Name == "" {
+			errs = append(errs, field.Required(listenerPath.Child("name"), ""))
+		} else if names.Has(listener.Name) {
+			errs = append(errs, field.Duplicate(listenerPath.Child("name"), listener.Name))
+		} else {
+			for _, msg := range validation.IsDNS1123Label(listener.Name) {
+				errs = append(errs, field.Invalid(listenerPath.Child("name"), listener.Name, msg))
+			}
+		}
+		names.Insert(listener.Name)
+
+		if len(listener.Protocols) == 0 {
+			errs = append(errs, field.Required(listenerPath.Child("protocols"), ""))
+		}
+		// the protocols are kept as written, they are matched case insensitively
+		for j, protocol := range listener.Protocols {
+			if _, ok := canonicalProtocol(string(protocol)); !ok {
+				errs = append(errs, field.NotSupported(listenerPath.Child("protocols").Index(j), protocol, supportedProtocols()))
+			}
+		}
+
+		if listener.Port != 0 {
+			for _, msg := range validation.IsValidPortNum(int(listener.Port)) {
+				errs = append(errs, field.Invalid(listenerPath.Child("port"), listener.Port, msg))
+			}
+			if listener.PortRange != nil {
+				errs = append(errs, field.Forbidden(listenerPath.Child("portRange"), "may not be set with port"))
+			}
+		}
+		if portRange := listener.PortRange; portRange != nil {
+			rangePath := listenerPath.Child("portRange")
+			for _, msg := range validation.IsValidPortNum(int(portRange.From)) {
+				errs = append(errs, field.Invalid(rangePath.Child("from"), portRange.From, msg))
+			}
+			for _, msg := range validation.IsValidPortNum(int(portRange.To)) {
+				errs = append(errs, field.Invalid(rangePath.Child("to"), portRange.To, msg))
+			}
+			if portRange.To < portRange.From {
+				errs = append(errs, field.Invalid(rangePath.Child("to"), portRange.To, "must not be less than from"))
+			}
+		}
+
+		for j, hostname := range listener.Hostnames {
+			errs = append(errs, validateHostname(listenerPath.Child("hostnames").Index(j), hostname)...)
+		}
+
+		if len(listener.TLSModes) != 0 && !listener.servesTLS() {
+			errs = append(errs, field.Forbidden(listenerPath.Child("tlsModes"), "requires protocol HTTPS or TLS"))
+		}
+		for j, mode := range listener.TLSModes {
+			if mode != apisv1.TLSModeTerminate && mode != apisv1.TLSModePassthrough {
+				errs = append(errs, field.NotSupported(listenerPath.Child("tlsModes").Index(j), mode,
+					[]string{string(apisv1.TLSModeTerminate), string(apisv1.TLSModePassthrough)}))
+			}
+		}
+
+		if listener.AllowedRoutes != nil && listener.AllowedRoutes.Namespaces != nil && listener.AllowedRoutes.Namespaces.From != nil {
+			switch from := *listener.AllowedRoutes.Namespaces.From; from {
+			case apisv1.NamespacesFromAll, apisv1.NamespacesFromSame, apisv1.NamespacesFromSelector:
+			default:
+				errs = append(errs, field.NotSupported(listenerPath.Child("allowedRoutes", "namespaces", "from"), from,
+					[]string{string(apisv1.NamespacesFromAll), string(apisv1.NamespacesFromSame), string(apisv1.NamespacesFromSelector)}))
+			}
+		}
+	}
+	return errs
+}
+
+func (l *Listener) servesTLS() bool {
+	for _, protocol := range l.Protocols {
+		if strings.EqualFold(string(protocol), string(apisv1.HTTPSProtocolType)) || strings.EqualFold(string(protocol), string(apisv1.TLSProtocolType)) {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidateListeners checks that every listener of gateway uses a port,
+// protocol, hostname and TLS mode advertised by one of the listener templates
+// of its GatewayClass.
+func ValidateListeners(gateway *apisv1.Gateway, templates []Listener) field.ErrorList {
+	var errs field.ErrorList
+	for i, listener := range gateway.Spec.Listeners {
+		if !matchListener(listener, templates) {
+			errs = append(errs, field.Invalid(field.NewPath("spec", "listeners").Index(i), fmt.Sprintf("%s/%d", listener.Protocol, listener.Port),
+				fmt.Sprintf("port, protocol, hostname or TLS mode is not provided by gateway class %s", gateway.Spec.GatewayClassName)))
+		}
+	}
+	return errs
+}
+
+func matchListener(listener apisv1.Listener, templates []Listener) bool {
+	for _, template := range templates {
+		if template.Matches(listener) {
+			return true
+		}
+	}
+	return false
+}
+
+// Matches reports whether the listener is allowed by the template.
+func (l *Listener) Matches(listener apisv1.Listener) bool {
+	if !l.allowsPort(int32(listener.Port)) || !l.allowsProtocol(listener.Protocol) {
+		return false
+	}
+	if len(l.Hostnames) != 0 && !l.allowsHostname(hostnameOf(listener.Hostname)) {
+		return false
+	}
+	if len(l.TLSModes) != 0 && listener.TLS != nil {
+		mode := apisv1.TLSModeTerminate
+		if listener.TLS.Mode != nil {
+			mode = *listener.TLS.Mode
+		}
+		for _, allowed := range l.TLSModes {
+			if allowed == mode {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+func (l *Listener) allowsPort(port int32) bool {
+	if l.PortRange != nil {
+		return port >= l.PortRange.From && port <= l.PortRange.To
+	}
+	return l.Port == 0 || l.Port == port
+}
+
+func (l *Listener) allowsProtocol(protocol apisv1.ProtocolType) bool {
+	for _, p := range l.Protocols {
+		if strings.EqualFold(string(p), string(protocol)) {
+			return true
+		}
+	}
+	return false
+}
+
+// allowsHostname reports whether the hostname is one of the allowed hostnames
+// or under an allowed wildcard hostname, an empty hostname is never allowed.
+func (l *Listener) allowsHostname(hostname string) bool {
+	if hostname == "" {
+		return false
+	}
+	for _, allowed := range l.Hostnames {
+		if allowed == hostname {
+			return true
+		}
+		if strings.HasPrefix(allowed, "*.") && strings.HasSuffix(hostname, allowed[1:]) {
+			return true
+		}
+	}
+	return false
+}
+
+func canonicalProtocol(protocol string) (apisv1.ProtocolType, bool) {
+	for _, p := range protocols {
+		if strings.EqualFold(string(p), strings.TrimSpace(protocol)) {
+			return p, true
+		}
+	}
+	return "", false
+}
+
+func supportedProtocols() []string {
+	supported := make([]string, 0, len(protocols))
+	for _, p := range protocols {
+		supported = append(supported, string(p))
+	}
+	return supported
+}
+
+// splitList splits a comma separated list, the items are trimmed and empty
+// items are dropped.
+func splitList(value string) []string {
+	items := make([]string, 0)
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
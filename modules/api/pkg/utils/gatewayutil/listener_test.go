@@ -0,0 +1,160 @@
+package gatewayutil
+
+import (
+	"reflect"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	apisv1 "sigs.k8s.io/gateway-api/apis/v1"
+)
+
+func TestParseListeners(t *testing.T) {
+	tests := []struct {
+		name        string
+		annotations map[string]string
+		want        []Listener
+		wantErr     bool
+		// wantExpanded are the protocols of the listeners expanded from the
+		// templates without hostnames and certificate
+		wantExpanded []apisv1.ProtocolType
+	}{
+		{
+			name: "legacy annotations keep protocols as written",
+			annotations: map[string]string{
+				AnnotationListener: "web, websecure",
+				"gatewayapi.kubesphere.io/listener.web.protocols":       "tcp, http",
+				"gatewayapi.kubesphere.io/listener.web.port":            "8000",
+				"gatewayapi.kubesphere.io/listener.websecure.protocols": "tls,https",
+				"gatewayapi.kubesphere.io/listener.websecure.port":      "8443",
+			},
+			want: []Listener{
+				{Name: "web", Protocols: []apisv1.ProtocolType{"tcp", "http"}, Port: 8000},
+				{Name: "websecure", Protocols: []apisv1.ProtocolType{"tls", "https"}, Port: 8443},
+			},
+			wantExpanded: []apisv1.ProtocolType{apisv1.HTTPProtocolType, apisv1.TLSProtocolType},
+		},
+		{
+			name: "legacy annotations without listeners",
+			annotations: map[string]string{
+				AnnotationListener: " , ",
+			},
+			wantErr: true,
+		},
+		{
+			name: "legacy annotations with unsupported protocol",
+			annotations: map[string]string{
+				AnnotationListener: "web",
+				"gatewayapi.kubesphere.io/listener.web.protocols": "http,quic",
+			},
+			wantErr: true,
+		},
+		{
+			name: "legacy annotations with invalid port",
+			annotations: map[string]string{
+				AnnotationListener: "web",
+				"gatewayapi.kubesphere.io/listener.web.protocols": "http",
+				"gatewayapi.kubesphere.io/listener.web.port":      "http",
+			},
+			wantErr: true,
+		},
+		{
+			name: "versioned templates take precedence",
+			annotations: map[string]string{
+				AnnotationListener: "legacy",
+				AnnotationListenerTemplates: `
+version: v1alpha1
+listeners:
+- name: web
+  protocols: [TCP, Http]
+  port: 8000
+- name: websecure
+  protocols: [TLS, HTTPS]
+  portRange: {from: 8443, to: 8453}
+  hostnames: ["*.example.com"]
+  tlsModes: [Terminate]
+`,
+			},
+			want: []Listener{
+				{Name: "web", Protocols: []apisv1.ProtocolType{"TCP", "Http"}, Port: 8000},
+				{Name: "websecure", Protocols: []apisv1.ProtocolType{"TLS", "HTTPS"}, PortRange: &PortRange{From: 8443, To: 8453},
+					Hostnames: []string{"*.example.com"}, TLSModes: []apisv1.TLSModeType{apisv1.TLSModeTerminate}},
+			},
+		},
+		{
+			name: "unsupported version",
+			annotations: map[string]string{
+				AnnotationListenerTemplates: "version: v2\nlisteners: [{name: web, protocols: [HTTP]}]",
+			},
+			wantErr: true,
+		},
+		{
+			name: "unknown field",
+			annotations: map[string]string{
+				AnnotationListenerTemplates: "version: v1alpha1\nlisteners: [{name: web, protocol: HTTP}]",
+			},
+			wantErr: true,
+		},
+		{
+			name: "duplicated names",
+			annotations: map[string]string{
+				AnnotationListenerTemplates: "version: v1alpha1\nlisteners: [{name: web, protocols: [HTTP]}, {name: web, protocols: [HTTPS]}]",
+			},
+			wantErr: true,
+		},
+		{
+			name: "port with port range",
+			annotations: map[string]string{
+				AnnotationListenerTemplates: "version: v1alpha1\nlisteners: [{name: web, protocols: [HTTP], port: 80, portRange: {from: 80, to: 90}}]",
+			},
+			wantErr: true,
+		},
+		{
+			name: "reversed port range",
+			annotations: map[string]string{
+				AnnotationListenerTemplates: "version: v1alpha1\nlisteners: [{name: web, protocols: [HTTP], portRange: {from: 90, to: 80}}]",
+			},
+			wantErr: true,
+		},
+		{
+			name: "TLS modes without TLS",
+			annotations: map[string]string{
+				AnnotationListenerTemplates: "version: v1alpha1\nlisteners: [{name: web, protocols: [HTTP], tlsModes: [Terminate]}]",
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gatewayClass := &apisv1.GatewayClass{ObjectMeta: metav1.ObjectMeta{Name: "class", Annotations: tt.annotations}}
+			got, err := ParseListeners(gatewayClass)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseListeners() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseListeners() = %+v, want %+v", got, tt.want)
+			}
+			if tt.wantExpanded == nil {
+				return
+			}
+
+			listeners, errs := ExpandListeners(got, nil, nil)
+			if len(errs) != 0 {
+				t.Fatalf("ExpandListeners() errors = %v", errs)
+			}
+			protocols := make([]apisv1.ProtocolType, 0, len(listeners))
+			for _, listener := range listeners {
+				protocols = append(protocols, listener.Protocol)
+			}
+			if !reflect.DeepEqual(protocols, tt.wantExpanded) {
+				t.Errorf("ExpandListeners() protocols = %v, want %v", protocols, tt.wantExpanded)
+			}
+			gateway := &apisv1.Gateway{Spec: apisv1.GatewaySpec{GatewayClassName: "class", Listeners: listeners}}
+			if errs := ValidateListeners(gateway, got); len(errs) != 0 {
+				t.Errorf("ValidateListeners() errors = %v", errs)
+			}
+		})
+	}
+}
@@ -0,0 +1,159 @@
+package gatewayutil
+
+import (
+	"fmt"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	apisv1 "sigs.k8s.io/gateway-api/apis/v1"
+)
+
+// defaultPorts are the ports of listeners created from templates which
+// advertise any port.
+var defaultPorts = map[apisv1.ProtocolType]apisv1.PortNumber{
+	apisv1.HTTPProtocolType:  80,
+	apisv1.HTTPSProtocolType: 443,
+	apisv1.TLSProtocolType:   443,
+}
+
+// expandPreference is the order which the protocol of listeners created from
+// a template is picked in, the protocols of a template share the port so only
+// one of them is used.
+var expandPreference = []apisv1.ProtocolType{
+	apisv1.HTTPSProtocolType,
+	apisv1.HTTPProtocolType,
+	apisv1.TLSProtocolType,
+	apisv1.TCPProtocolType,
+	apisv1.UDPProtocolType,
+}
+
+// ExpandListeners expands the listener templates into listeners. The listeners
+// of a template use one protocol of it, HTTP and HTTPS are preferred over TLS
+// and TCP, and HTTPS is only picked with a certificate. One listener is created
+// for each hostname of the protocols routed by hostname. The certificate is
+// used by the listeners which terminate TLS, TLS listeners pass the connections
+// through if the template allows it. The AllowedRoutes of templates are copied,
+// and the namespaces of them are left for the caller to limit by the scope of
+// gateway.
+func ExpandListeners(templates []Listener, hostnames []string, certificate *apisv1.SecretObjectReference) ([]apisv1.Listener, field.ErrorList) {
+	var errs field.ErrorList
+	listeners := make([]apisv1.Listener, 0)
+	for _, template := range templates {
+		path := field.NewPath("listeners").Key(template.Name)
+		protocol, ok := template.expandProtocol(certificate)
+		if !ok {
+			errs = append(errs, field.NotSupported(path.Child("protocols"), template.Protocols, supportedProtocols()))
+			continue
+		}
+		port := apisv1.PortNumber(template.Port)
+		if template.PortRange != nil {
+			port = apisv1.PortNumber(template.PortRange.From)
+		}
+		if port == 0 {
+			port = defaultPorts[protocol]
+		}
+		if port == 0 {
+			errs = append(errs, field.Required(path.Child("port"), fmt.Sprintf("listener template %s does not provide a port for protocol %s", template.Name, protocol)))
+			continue
+		}
+
+		listener := apisv1.Listener{
+			Name:     apisv1.SectionName(fmt.Sprintf("%s-%s", template.Name, strings.ToLower(string(protocol)))),
+			Protocol: protocol,
+			Port:     port,
+		}
+		if template.AllowedRoutes != nil {
+			listener.AllowedRoutes = template.AllowedRoutes.DeepCopy()
+		}
+
+		switch protocol {
+		case apisv1.HTTPSProtocolType:
+			if !template.allowsTLSMode(apisv1.TLSModeTerminate) {
+				errs = append(errs, field.Forbidden(path.Child("tlsModes"), fmt.Sprintf("listener template %s does not allow HTTPS to terminate TLS", template.Name)))
+				continue
+			}
+			if certificate == nil {
+				errs = append(errs, field.Required(field.NewPath("tlsSecret"), fmt.Sprintf("required by HTTPS listener of template %s", template.Name)))
+				continue
+			}
+			mode := apisv1.TLSModeTerminate
+			listener.TLS = &apisv1.GatewayTLSConfig{Mode: &mode, CertificateRefs: []apisv1.SecretObjectReference{*certificate}}
+		case apisv1.TLSProtocolType:
+			mode := apisv1.TLSModePassthrough
+			var refs []apisv1.SecretObjectReference
+			if !template.allowsTLSMode(apisv1.TLSModePassthrough) || (certificate != nil && template.allowsTLSMode(apisv1.TLSModeTerminate)) {
+				if certificate == nil {
+					errs = append(errs, field.Required(field.NewPath("tlsSecret"), fmt.Sprintf("required by TLS listener of template %s", template.Name)))
+					continue
+				}
+				mode = apisv1.TLSModeTerminate
+				refs = []apisv1.SecretObjectReference{*certificate}
+			}
+			listener.TLS = &apisv1.GatewayTLSConfig{Mode: &mode, CertificateRefs: refs}
+		}
+
+		expanded := []apisv1.Listener{listener}
+		if routedByHostname(protocol) && len(hostnames) != 0 {
+			expanded = make([]apisv1.Listener, 0, len(hostnames))
+			for i, hostname := range hostnames {
+				l := *listener.DeepCopy()
+				h := apisv1.Hostname(hostname)
+				l.Hostname = &h
+				if len(hostnames) > 1 {
+					l.Name = apisv1.SectionName(fmt.Sprintf("%s-%d", listener.Name, i))
+				}
+				expanded = append(expanded, l)
+			}
+		}
+		for _, l := range expanded {
+			for _, msg := range validation.IsDNS1123Subdomain(string(l.Name)) {
+				errs = append(errs, field.Invalid(path.Child("name"), l.Name, msg))
+			}
+		}
+		listeners = append(listeners, expanded...)
+	}
+	return listeners, errs
+}
+
+// expandProtocol returns the protocol of the listeners created from the
+// template. HTTPS is skipped without a certificate if the template has other
+// protocols, so that the listeners can be created.
+func (l *Listener) expandProtocol(certificate *apisv1.SecretObjectReference) (apisv1.ProtocolType, bool) {
+	var candidates []apisv1.ProtocolType
+	for _, protocol := range expandPreference {
+		if l.allowsProtocol(protocol) {
+			candidates = append(candidates, protocol)
+		}
+	}
+	if len(candidates) == 0 {
+		return "", false
+	}
+	if candidates[0] == apisv1.HTTPSProtocolType && len(candidates) > 1 && (certificate == nil || !l.allowsTLSMode(apisv1.TLSModeTerminate)) {
+		return candidates[1], true
+	}
+	return candidates[0], true
+}
+
+func (l *Listener) allowsTLSMode(mode apisv1.TLSModeType) bool {
+	if len(l.TLSModes) == 0 {
+		return true
+	}
+	for _, m := range l.TLSModes {
+		if m == mode {
+			return true
+		}
+	}
+	return false
+}
+
+// routedByHostname reports whether listeners of the protocol tell requests
+// apart by hostname.
+func routedByHostname(protocol apisv1.ProtocolType) bool {
+	switch protocol {
+	case apisv1.HTTPProtocolType, apisv1.HTTPSProtocolType, apisv1.TLSProtocolType:
+		return true
+	default:
+		return false
+	}
+}
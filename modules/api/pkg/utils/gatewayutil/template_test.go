@@ -0,0 +1,101 @@
+package gatewayutil
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	apisv1 "sigs.k8s.io/gateway-api/apis/v1"
+)
+
+func TestExpandListeners(t *testing.T) {
+	certificate := &apisv1.SecretObjectReference{Name: "tls"}
+	tests := []struct {
+		name        string
+		templates   []Listener
+		hostnames   []string
+		certificate *apisv1.SecretObjectReference
+		// want are the listeners in the form of name/protocol/port/hostname/tls mode
+		want    []string
+		wantErr bool
+	}{
+		{
+			name:      "HTTP is preferred over TCP on the same port",
+			templates: []Listener{{Name: "web", Protocols: []apisv1.ProtocolType{"tcp", "http"}, Port: 8000}},
+			want:      []string{"web-http/HTTP/8000//"},
+		},
+		{
+			name:        "HTTPS is preferred over TLS with a certificate",
+			templates:   []Listener{{Name: "websecure", Protocols: []apisv1.ProtocolType{"TLS", "HTTPS"}, PortRange: &PortRange{From: 8443, To: 8453}}},
+			hostnames:   []string{"a.example.com"},
+			certificate: certificate,
+			want:        []string{"websecure-https/HTTPS/8443/a.example.com/Terminate"},
+		},
+		{
+			name:      "TLS passes through without a certificate",
+			templates: []Listener{{Name: "websecure", Protocols: []apisv1.ProtocolType{"TLS", "HTTPS"}, Port: 8443}},
+			want:      []string{"websecure-tls/TLS/8443//Passthrough"},
+		},
+		{
+			name:      "HTTPS requires a certificate",
+			templates: []Listener{{Name: "websecure", Protocols: []apisv1.ProtocolType{"HTTPS"}, Port: 8443}},
+			wantErr:   true,
+		},
+		{
+			name:      "TLS requires a certificate if passthrough is not allowed",
+			templates: []Listener{{Name: "websecure", Protocols: []apisv1.ProtocolType{"TLS"}, Port: 8443, TLSModes: []apisv1.TLSModeType{apisv1.TLSModeTerminate}}},
+			wantErr:   true,
+		},
+		{
+			name:      "one listener for each hostname",
+			templates: []Listener{{Name: "web", Protocols: []apisv1.ProtocolType{"HTTP"}}},
+			hostnames: []string{"a.example.com", "b.example.com"},
+			want:      []string{"web-http-0/HTTP/80/a.example.com/", "web-http-1/HTTP/80/b.example.com/"},
+		},
+		{
+			name:      "hostnames are ignored by TCP",
+			templates: []Listener{{Name: "db", Protocols: []apisv1.ProtocolType{"TCP"}, Port: 5432}},
+			hostnames: []string{"a.example.com"},
+			want:      []string{"db-tcp/TCP/5432//"},
+		},
+		{
+			name:      "TCP without port",
+			templates: []Listener{{Name: "db", Protocols: []apisv1.ProtocolType{"TCP"}}},
+			wantErr:   true,
+		},
+		{
+			name:      "too long name",
+			templates: []Listener{{Name: strings.Repeat("a", 250), Protocols: []apisv1.ProtocolType{"HTTP"}}},
+			wantErr:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			listeners, errs := ExpandListeners(tt.templates, tt.hostnames, tt.certificate)
+			if (len(errs) != 0) != tt.wantErr {
+				t.Fatalf("ExpandListeners() errors = %v, wantErr %v", errs, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			got := make([]string, 0, len(listeners))
+			for _, l := range listeners {
+				mode := ""
+				if l.TLS != nil && l.TLS.Mode != nil {
+					mode = string(*l.TLS.Mode)
+				}
+				got = append(got, fmt.Sprintf("%s/%s/%d/%s/%s", l.Name, l.Protocol, l.Port, hostnameOf(l.Hostname), mode))
+			}
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("ExpandListeners() = %v, want %v", got, tt.want)
+			}
+			for i := range listeners {
+				for j := i + 1; j < len(listeners); j++ {
+					if listeners[i].Port == listeners[j].Port && !protocolsCompatible(listeners[i].Protocol, listeners[j].Protocol) {
+						t.Errorf("listeners %s and %s can not share port %d", listeners[i].Name, listeners[j].Name, listeners[i].Port)
+					}
+				}
+			}
+		})
+	}
+}
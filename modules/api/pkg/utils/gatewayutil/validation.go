@@ -0,0 +1,159 @@
+package gatewayutil
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	apisv1 "sigs.k8s.io/gateway-api/apis/v1"
+)
+
+// Validation is the result of validating a gateway.
+type Validation struct {
+	Warnings []string
+	// Errors include the errors of the Conflicts of new or changed listeners,
+	// the other Conflicts are warnings
+	Errors    field.ErrorList
+	Conflicts []ListenerConflict
+}
+
+// ValidateGateway validates the scope labels and listeners of gateway, and the
+// listeners against the listener templates of its GatewayClass and the
+// listeners of the other gateways of the class. The old gateway is the one
+// being updated, nil for a creation. The existing conflicts of the listeners
+// which the update does not change are warnings, so that they do not block
+// other changes. The returned error is only for failures of reading the
+// GatewayClass and gateways.
+func ValidateGateway(ctx context.Context, reader client.Reader, gateway, old *apisv1.Gateway) (*Validation, error) {
+	result := &Validation{}
+	if err := validateScopeLabels(gateway); err != nil {
+		result.Errors = append(result.Errors, err)
+	}
+	result.Errors = append(result.Errors, ValidateGatewayListeners(gateway)...)
+
+	gatewayClass := &apisv1.GatewayClass{}
+	err := reader.Get(ctx, types.NamespacedName{Name: string(gateway.Spec.GatewayClassName)}, gatewayClass)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			result.Warnings = append(result.Warnings, fmt.Sprintf("gateway class %s not found, the listeners are not validated", gateway.Spec.GatewayClassName))
+			return result, nil
+		}
+		return nil, err
+	}
+	// classes which do not advertise listeners are not managed by KubeSphere,
+	// so their gateways are left to the controller.
+	if !HasListenerTemplates(gatewayClass) {
+		return result, nil
+	}
+	templates, err := ParseListeners(gatewayClass)
+	if err != nil {
+		result.Errors = append(result.Errors, field.Invalid(field.NewPath("spec", "gatewayClassName"), gateway.Spec.GatewayClassName, err.Error()))
+		return result, nil
+	}
+	result.Errors = append(result.Errors, ValidateListeners(gateway, templates)...)
+
+	// the gateways of a class managed by KubeSphere share the data plane, so
+	// their listeners must not conflict with each other.
+	result.Conflicts, err = FindListenerConflicts(ctx, reader, gateway)
+	if err != nil {
+		return nil, err
+	}
+	for _, conflict := range result.Conflicts {
+		if old != nil && listenerUnchanged(old, gateway.Spec.Listeners[conflict.index]) {
+			result.Warnings = append(result.Warnings, fmt.Sprintf("listener %s %s", conflict.Listener, conflict.Message))
+			continue
+		}
+		result.Errors = append(result.Errors, conflict.FieldError())
+	}
+	return result, nil
+}
+
+// listenerUnchanged reports whether the old gateway has the listener with the
+// same port, protocol and hostname.
+func listenerUnchanged(old *apisv1.Gateway, listener apisv1.Listener) bool {
+	for _, l := range old.Spec.Listeners {
+		if l.Name == listener.Name {
+			return l.Port == listener.Port && l.Protocol == listener.Protocol && hostnameOf(l.Hostname) == hostnameOf(listener.Hostname)
+		}
+	}
+	return false
+}
+
+// ValidateGatewayListeners checks that the listeners of gateway have unique
+// names, valid hostnames and do not conflict with each other. Listeners on the
+// same port conflict if their protocols can not share the port, or if they have
+// the same protocol and hostname.
+func ValidateGatewayListeners(gateway *apisv1.Gateway) field.ErrorList {
+	var errs field.ErrorList
+	listenersPath := field.NewPath("spec", "listeners")
+	names := map[apisv1.SectionName]bool{}
+	for i, listener := range gateway.Spec.Listeners {
+		path := listenersPath.Index(i)
+		if names[listener.Name] {
+			errs = append(errs, field.Duplicate(path.Child("name"), listener.Name))
+		}
+		names[listener.Name] = true
+
+		if listener.Hostname != nil {
+			errs = append(errs, validateHostname(path.Child("hostname"), string(*listener.Hostname))...)
+		}
+
+		for j := 0; j < i; j++ {
+			other := gateway.Spec.Listeners[j]
+			if other.Port != listener.Port {
+				continue
+			}
+			if !protocolsCompatible(other.Protocol, listener.Protocol) {
+				errs = append(errs, field.Invalid(path.Child("protocol"), listener.Protocol,
+					fmt.Sprintf("conflicts with protocol %s of listener %s on port %d", other.Protocol, other.Name, listener.Port)))
+				continue
+			}
+			if other.Protocol == listener.Protocol && hostnameOf(other.Hostname) == hostnameOf(listener.Hostname) {
+				errs = append(errs, field.Duplicate(path.Child("hostname"), hostnameOf(listener.Hostname)))
+			}
+		}
+	}
+	return errs
+}
+
+// protocolsCompatible reports whether listeners of two protocols can share a
+// port, UDP is served apart from the protocols over TCP, and HTTPS and TLS
+// listeners are told apart by SNI.
+func protocolsCompatible(a, b apisv1.ProtocolType) bool {
+	if a == b {
+		return true
+	}
+	if (a == apisv1.UDPProtocolType) != (b == apisv1.UDPProtocolType) {
+		return true
+	}
+	tlsProtocols := map[apisv1.ProtocolType]bool{apisv1.HTTPSProtocolType: true, apisv1.TLSProtocolType: true}
+	return tlsProtocols[a] && tlsProtocols[b]
+}
+
+func hostnameOf(hostname *apisv1.Hostname) string {
+	if hostname == nil {
+		return ""
+	}
+	return string(*hostname)
+}
+
+// validateHostname checks a listener hostname, which is a DNS subdomain that
+// may be prefixed with a wildcard label.
+func validateHostname(path *field.Path, hostname string) field.ErrorList {
+	var errs field.ErrorList
+	if hostname == "" {
+		return errs
+	}
+	if ip := validation.IsValidIP(path, hostname); len(ip) == 0 {
+		return append(errs, field.Invalid(path, hostname, "must be a hostname rather than an IP address"))
+	}
+	for _, msg := range validation.IsDNS1123Subdomain(strings.TrimPrefix(hostname, "*.")) {
+		errs = append(errs, field.Invalid(path, hostname, msg))
+	}
+	return errs
+}